@@ -10,6 +10,8 @@ import (
 	"github.com/vesoft-inc/nebula-br/pkg/cleanup"
 	"github.com/vesoft-inc/nebula-br/pkg/config"
 	"github.com/vesoft-inc/nebula-br/pkg/log"
+	"github.com/vesoft-inc/nebula-br/pkg/progress"
+	"github.com/vesoft-inc/nebula-br/pkg/report"
 )
 
 func NewBackupCmd() *cobra.Command {
@@ -21,7 +23,9 @@ func NewBackupCmd() *cobra.Command {
 
 	config.AddCommonFlags(backupCmd.PersistentFlags())
 	config.AddBackupFlags(backupCmd.PersistentFlags())
+	config.AddReportFlags(backupCmd.PersistentFlags())
 	backupCmd.AddCommand(newFullBackupCmd())
+	backupCmd.AddCommand(newIncrBackupCmd())
 	return backupCmd
 }
 
@@ -30,51 +34,91 @@ func newFullBackupCmd() *cobra.Command {
 		Use:   "full",
 		Short: "Full backup Nebula Graph Database",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			err := log.SetLog(cmd.Flags())
-			if err != nil {
-				return fmt.Errorf("init logger failed: %w", err)
-			}
+			return runBackup(cmd)
+		},
+	}
 
-			cfg := &config.BackupConfig{}
-			err = cfg.ParseFlags(cmd.Flags())
-			if err != nil {
-				return fmt.Errorf("parse flags failed: %w", err)
-			}
+	return fullBackupCmd
+}
 
-			b, err := backup.NewBackup(context.TODO(), cfg)
-			if err != nil {
-				return err
-			}
+func newIncrBackupCmd() *cobra.Command {
+	incrBackupCmd := &cobra.Command{
+		Use:   "incr",
+		Short: "Incremental backup Nebula Graph Database based on a previous backup",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBackup(cmd)
+		},
+	}
+
+	config.AddIncrBackupFlags(incrBackupCmd.Flags())
+	return incrBackupCmd
+}
+
+func runBackup(cmd *cobra.Command) (err error) {
+	rep := report.New(cmd.CommandPath())
+	defer func() {
+		writeReport(cmd, rep, err)
+	}()
+
+	err = log.SetLog(cmd.Flags())
+	if err != nil {
+		return fmt.Errorf("init logger failed: %w", err)
+	}
 
-			fmt.Println("Start to backup cluster...")
-			backupName, err := b.Backup()
-			if err != nil {
-				fmt.Printf("Backup failed: %v, will try to clean the remaining garbage...\n", err)
-
-				if backupName != "" {
-					cleanCfg := &config.CleanupConfig{
-						BackupName: backupName,
-						Backend:    cfg.Backend,
-						MetaAddr:   cfg.MetaAddr,
-					}
-					c, err := cleanup.NewCleanup(context.TODO(), cleanCfg)
-					if err != nil {
-						return fmt.Errorf("create cleanup for %s failed: %w", backupName, err)
-					}
-
-					err = c.Clean()
-					if err != nil {
-						return fmt.Errorf("cleanup %s failed when backup failed: %w", backupName, err)
-					}
-					fmt.Printf("Cleanup backup %s successfully after backup failed.", backupName)
-				}
-				return err
+	// the progress view is rendered in terminal instead of the detail logs, except warnings
+	if progress.Terminal() {
+		log.StderrForWarn()
+	}
+
+	cfg := &config.BackupConfig{}
+	err = cfg.ParseFlags(cmd.Flags())
+	if err != nil {
+		return fmt.Errorf("parse flags failed: %w", err)
+	}
+
+	b, err := backup.NewBackup(context.TODO(), cfg)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Start to backup cluster...")
+	backupName, err := b.Backup()
+	rep.BackupName = backupName
+	b.Report(rep)
+	if err != nil {
+		fmt.Printf("Backup failed: %v, will try to clean the remaining garbage...\n", err)
+
+		if backupName != "" {
+			rep.CleanupRan = true
+			if cerr := cleanAfterFailure(cfg, backupName); cerr != nil {
+				rep.CleanupError = cerr.Error()
+				rep.AddError(err)
+				return cerr
 			}
+			fmt.Printf("Cleanup backup %s successfully after backup failed.", backupName)
+		}
+		return err
+	}
 
-			fmt.Println("Backup succeed.")
-			return nil
-		},
+	fmt.Println("Backup succeed.")
+	return nil
+}
+
+func cleanAfterFailure(cfg *config.BackupConfig, backupName string) error {
+	cleanCfg := &config.CleanupConfig{
+		BackupName: backupName,
+		Backend:    cfg.Backend,
+		MetaAddr:   cfg.MetaAddr,
+		Conn:       cfg.Conn,
+	}
+	c, err := cleanup.NewCleanup(context.TODO(), cleanCfg)
+	if err != nil {
+		return fmt.Errorf("create cleanup for %s failed: %w", backupName, err)
 	}
 
-	return fullBackupCmd
+	err = c.Clean()
+	if err != nil {
+		return fmt.Errorf("cleanup %s failed when backup failed: %w", backupName, err)
+	}
+	return nil
 }
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vesoft-inc/nebula-br/pkg/check"
+	"github.com/vesoft-inc/nebula-br/pkg/config"
+	"github.com/vesoft-inc/nebula-br/pkg/log"
+)
+
+func NewCheckCmd() *cobra.Command {
+	checkCmd := &cobra.Command{
+		Use:          "check",
+		Short:        "Check whether the cluster and external storage are ready for backup and restore",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := log.SetLog(cmd.Flags())
+			if err != nil {
+				return fmt.Errorf("init logger failed: %w", err)
+			}
+
+			cfg := &config.CheckConfig{}
+			err = cfg.ParseFlags(cmd.Flags())
+			if err != nil {
+				return err
+			}
+
+			return check.NewCheck(context.TODO(), cfg).Check()
+		},
+	}
+
+	config.AddCommonFlags(checkCmd.PersistentFlags())
+	config.AddCheckFlags(checkCmd.PersistentFlags())
+	return checkCmd
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/vesoft-inc/nebula-br/pkg/cleanup"
 	"github.com/vesoft-inc/nebula-br/pkg/config"
 	"github.com/vesoft-inc/nebula-br/pkg/log"
+	"github.com/vesoft-inc/nebula-br/pkg/report"
 )
 
 func NewCleanupCmd() *cobra.Command {
@@ -16,8 +17,13 @@ func NewCleanupCmd() *cobra.Command {
 		Use:          "cleanup",
 		Short:        "Cleanup backup files in external storage and nebula cluster",
 		SilenceUsage: true,
-		RunE: func(cmd *cobra.Command, args []string) error {
-			err := log.SetLog(cmd.Flags())
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			rep := report.New(cmd.CommandPath())
+			defer func() {
+				writeReport(cmd, rep, err)
+			}()
+
+			err = log.SetLog(cmd.Flags())
 			if err != nil {
 				return fmt.Errorf("init logger failed: %w", err)
 			}
@@ -34,6 +40,7 @@ func NewCleanupCmd() *cobra.Command {
 			}
 
 			err = c.Clean()
+			c.Report(rep)
 			if err != nil {
 				return err
 			}
@@ -44,5 +51,6 @@ func NewCleanupCmd() *cobra.Command {
 
 	config.AddCommonFlags(cleanupCmd.PersistentFlags())
 	config.AddCleanupFlags(cleanupCmd.PersistentFlags())
+	config.AddReportFlags(cleanupCmd.PersistentFlags())
 	return cleanupCmd
 }
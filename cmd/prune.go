@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vesoft-inc/nebula-br/pkg/cleanup"
+	"github.com/vesoft-inc/nebula-br/pkg/config"
+	"github.com/vesoft-inc/nebula-br/pkg/log"
+)
+
+func NewPruneCmd() *cobra.Command {
+	pruneCmd := &cobra.Command{
+		Use:          "prune",
+		Short:        "Delete the backups in external storage which are not kept by the retention policy",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := log.SetLog(cmd.Flags())
+			if err != nil {
+				return fmt.Errorf("init logger failed: %w", err)
+			}
+
+			cfg := &config.PruneConfig{}
+			err = cfg.ParseFlags(cmd.Flags())
+			if err != nil {
+				return err
+			}
+
+			p, err := cleanup.NewPrune(context.TODO(), cfg)
+			if err != nil {
+				return err
+			}
+
+			return p.Prune()
+		},
+	}
+
+	config.AddCommonFlags(pruneCmd.PersistentFlags())
+	config.AddPruneFlags(pruneCmd.PersistentFlags())
+	return pruneCmd
+}
@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vesoft-inc/nebula-br/pkg/config"
+	"github.com/vesoft-inc/nebula-br/pkg/report"
+)
+
+// writeReport writes the report with the result of command if --report is specified,
+// failing to write the report does not change the result of command
+func writeReport(cmd *cobra.Command, rep *report.Report, err error) {
+	path, ferr := cmd.Flags().GetString(config.FlagReport)
+	if ferr != nil || path == "" {
+		return
+	}
+
+	if werr := rep.Write(path, err); werr != nil {
+		fmt.Printf("Write report failed: %v\n", werr)
+	}
+}
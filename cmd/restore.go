@@ -8,6 +8,8 @@ import (
 
 	"github.com/vesoft-inc/nebula-br/pkg/config"
 	"github.com/vesoft-inc/nebula-br/pkg/log"
+	"github.com/vesoft-inc/nebula-br/pkg/progress"
+	"github.com/vesoft-inc/nebula-br/pkg/report"
 	"github.com/vesoft-inc/nebula-br/pkg/restore"
 )
 
@@ -17,9 +19,9 @@ func NewRestoreCmd() *cobra.Command {
 		Short:        "Restore Nebula Graph Database, notice that it will restart the cluster",
 		SilenceUsage: true,
 	}
-	config.AddCommonFlags(restoreCmd.PersistentFlags())
-	config.AddRestoreFlags(restoreCmd.PersistentFlags())
 	restoreCmd.AddCommand(newFullRestoreCmd())
+	restoreCmd.AddCommand(newResumeRestoreCmd())
+	restoreCmd.AddCommand(newRollbackRestoreCmd())
 	return restoreCmd
 }
 
@@ -27,12 +29,22 @@ func newFullRestoreCmd() *cobra.Command {
 	fullRestoreCmd := &cobra.Command{
 		Use:   "full",
 		Short: "full restore Nebula Graph Database",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			err := log.SetLog(cmd.Flags())
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			rep := report.New(cmd.CommandPath())
+			defer func() {
+				writeReport(cmd, rep, err)
+			}()
+
+			err = log.SetLog(cmd.Flags())
 			if err != nil {
 				return fmt.Errorf("init logger failed: %w", err)
 			}
 
+			// the progress view is rendered in terminal instead of the detail logs, except warnings
+			if progress.Terminal() {
+				log.StderrForWarn()
+			}
+
 			cfg := &config.RestoreConfig{}
 			err = cfg.ParseFlags(cmd.Flags())
 			if err != nil {
@@ -44,16 +56,25 @@ func newFullRestoreCmd() *cobra.Command {
 				return err
 			}
 
+			rep.BackupName = cfg.BackupName
+			if cfg.DryRun {
+				return r.DryRun()
+			}
+
 			err = r.Restore()
+			r.Report(rep)
 			if err != nil {
 				f, ferr := restore.NewFixFrom(r)
 				if ferr != nil {
 					return err
 				}
 
+				rep.FixRan = true
 				ferr = f.Fix()
 				if ferr != nil {
+					rep.FixError = ferr.Error()
 					fmt.Println("Fix failed when restore failed", ferr)
+					fmt.Printf("Run 'restore resume' or 'restore rollback' with journal %s later.\n", cfg.JournalPath)
 				}
 
 				return err
@@ -63,5 +84,117 @@ func newFullRestoreCmd() *cobra.Command {
 		},
 	}
 
+	config.AddCommonFlags(fullRestoreCmd.Flags())
+	config.AddRestoreFlags(fullRestoreCmd.Flags())
+	config.AddFullRestoreFlags(fullRestoreCmd.Flags())
+	config.AddReportFlags(fullRestoreCmd.Flags())
 	return fullRestoreCmd
 }
+
+func newResumeRestoreCmd() *cobra.Command {
+	resumeCmd := &cobra.Command{
+		Use:   "resume",
+		Short: "resume an interrupted restore by its journal",
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			rep := report.New(cmd.CommandPath())
+			defer func() {
+				writeReport(cmd, rep, err)
+			}()
+
+			err = log.SetLog(cmd.Flags())
+			if err != nil {
+				return fmt.Errorf("init logger failed: %w", err)
+			}
+
+			// the progress view is rendered in terminal instead of the detail logs, except warnings
+			if progress.Terminal() {
+				log.StderrForWarn()
+			}
+
+			// the storage is taken from journal if not specified
+			if !cmd.Flags().Changed(config.FlagStorage) {
+				journalPath, _ := cmd.Flags().GetString(config.FlagJournal)
+				uri, err := restore.StorageOfJournal(journalPath)
+				if err != nil {
+					return err
+				}
+				if err = cmd.Flags().Set(config.FlagStorage, uri); err != nil {
+					return err
+				}
+			}
+
+			cfg := &config.RestoreConfig{}
+			err = cfg.ParseFlags(cmd.Flags())
+			if err != nil {
+				return err
+			}
+
+			r, err := restore.NewRestoreFromJournal(context.TODO(), cfg)
+			if err != nil {
+				return err
+			}
+
+			err = r.Resume()
+			r.Report(rep)
+			if err != nil {
+				fmt.Printf("Resume failed, run 'restore resume' again or 'restore rollback' with journal %s.\n", cfg.JournalPath)
+				return err
+			}
+			fmt.Println("Restore succeed.")
+			return nil
+		},
+	}
+
+	config.AddCommonFlags(resumeCmd.Flags())
+	config.AddResumeFlags(resumeCmd.Flags())
+	config.AddReportFlags(resumeCmd.Flags())
+	return resumeCmd
+}
+
+func newRollbackRestoreCmd() *cobra.Command {
+	rollbackCmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "rollback a failed or interrupted restore, move back the origin data and restart the cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := log.SetLog(cmd.Flags())
+			if err != nil {
+				return fmt.Errorf("init logger failed: %w", err)
+			}
+
+			cfg := &config.RollbackConfig{}
+			err = cfg.ParseFlags(cmd.Flags())
+			if err != nil {
+				return err
+			}
+
+			// the storage is taken from journal if not specified when rollback by journal
+			if cfg.Backend == nil && cfg.Suffix == "" {
+				uri, err := restore.StorageOfJournal(cfg.JournalPath)
+				if err != nil {
+					return fmt.Errorf("get storage from journal failed, please specify it by --%s: %w", config.FlagStorage, err)
+				}
+				if err = cmd.Flags().Set(config.FlagStorage, uri); err != nil {
+					return err
+				}
+				if err = cfg.ParseFlags(cmd.Flags()); err != nil {
+					return err
+				}
+			}
+
+			rb, err := restore.NewRollback(context.TODO(), cfg)
+			if err != nil {
+				return err
+			}
+			err = rb.Rollback()
+			if err != nil {
+				return err
+			}
+			fmt.Println("Rollback succeed.")
+			return nil
+		},
+	}
+
+	config.AddCommonFlags(rollbackCmd.Flags())
+	config.AddRollbackFlags(rollbackCmd.Flags())
+	return rollbackCmd
+}
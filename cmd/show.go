@@ -27,6 +27,9 @@ func NewShowCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if cfg.Output != config.OutputTable {
+				log.DisableStdout()
+			}
 
 			s, err := show.NewShow(context.TODO(), cfg)
 			if err != nil {
@@ -37,6 +40,7 @@ func NewShowCmd() *cobra.Command {
 		},
 	}
 	config.AddCommonFlags(showCmd.PersistentFlags())
+	config.AddShowFlags(showCmd.Flags())
 
 	return showCmd
 }
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vesoft-inc/nebula-br/pkg/config"
+	"github.com/vesoft-inc/nebula-br/pkg/log"
+	"github.com/vesoft-inc/nebula-br/pkg/report"
+	"github.com/vesoft-inc/nebula-br/pkg/validate"
+)
+
+func NewValidateCmd() *cobra.Command {
+	validateCmd := &cobra.Command{
+		Use:          "validate",
+		Short:        "Validate the spaces of a restored cluster against the backup it is restored from",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			rep := report.New(cmd.CommandPath())
+			defer func() {
+				writeReport(cmd, rep, err)
+			}()
+
+			err = log.SetLog(cmd.Flags())
+			if err != nil {
+				return fmt.Errorf("init logger failed: %w", err)
+			}
+
+			cfg := &config.ValidateConfig{}
+			err = cfg.ParseFlags(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			rep.BackupName = cfg.BackupName
+
+			v, err := validate.NewValidate(context.TODO(), cfg)
+			if err != nil {
+				return err
+			}
+			return v.Validate()
+		},
+	}
+
+	config.AddCommonFlags(validateCmd.PersistentFlags())
+	config.AddValidateFlags(validateCmd.PersistentFlags())
+	config.AddReportFlags(validateCmd.Flags())
+	return validateCmd
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vesoft-inc/nebula-br/pkg/config"
+	"github.com/vesoft-inc/nebula-br/pkg/log"
+	"github.com/vesoft-inc/nebula-br/pkg/verify"
+)
+
+func NewVerifyCmd() *cobra.Command {
+	verifyCmd := &cobra.Command{
+		Use:          "verify",
+		Short:        "Verify the backup files in external storage by its manifest, or only its structure without manifest, without touching the cluster",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := log.SetLog(cmd.Flags())
+			if err != nil {
+				return fmt.Errorf("init logger failed: %w", err)
+			}
+
+			cfg := &config.VerifyConfig{}
+			err = cfg.ParseFlags(cmd.Flags())
+			if err != nil {
+				return err
+			}
+
+			v, err := verify.NewVerify(context.TODO(), cfg)
+			if err != nil {
+				return err
+			}
+
+			return v.Verify()
+		},
+	}
+
+	config.AddCommonFlags(verifyCmd.PersistentFlags())
+	config.AddVerifyFlags(verifyCmd.PersistentFlags())
+	return verifyCmd
+}
@@ -13,4 +13,6 @@ require (
 	github.com/vesoft-inc/nebula-agent v0.1.1
 	github.com/vesoft-inc/nebula-go/v3 v3.3.1
 	golang.org/x/sys v0.0.0-20211124211545-fe61309f8881 // indirect
+	google.golang.org/grpc v1.41.0
+	gopkg.in/yaml.v2 v2.2.8
 )
@@ -5,14 +5,20 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/vesoft-inc/nebula-br/cmd"
+	"github.com/vesoft-inc/nebula-br/pkg/config"
 )
 
 func main() {
 	rootCmd := &cobra.Command{
 		Use:   "br",
 		Short: "Nebula br is a Nebula backup and restore tool",
+		// set the flags not in command line by env or config file, before checking required flags
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return config.LoadConfig(cmd.Flags())
+		},
 	}
-	rootCmd.AddCommand(cmd.NewBackupCmd(), cmd.NewVersionCmd(), cmd.NewRestoreCmd(), cmd.NewCleanupCmd(), cmd.NewShowCmd())
+	rootCmd.AddCommand(cmd.NewBackupCmd(), cmd.NewVersionCmd(), cmd.NewRestoreCmd(), cmd.NewCleanupCmd(), cmd.NewShowCmd(),
+		cmd.NewVerifyCmd(), cmd.NewPruneCmd(), cmd.NewCheckCmd(), cmd.NewValidateCmd())
 	if err := rootCmd.Execute(); err != nil {
 		log.Fatalln(err)
 	}
@@ -6,6 +6,7 @@ import (
 	"path"
 	"path/filepath"
 	"strconv"
+	"sync"
 
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
@@ -17,26 +18,54 @@ import (
 
 	"github.com/vesoft-inc/nebula-br/pkg/clients"
 	"github.com/vesoft-inc/nebula-br/pkg/config"
+	"github.com/vesoft-inc/nebula-br/pkg/progress"
+	"github.com/vesoft-inc/nebula-br/pkg/report"
 	"github.com/vesoft-inc/nebula-br/pkg/utils"
 )
 
+const (
+	phaseLoadBase       = "load base backup"
+	phaseCreateSnapshot = "create snapshot"
+	phaseUploadMeta     = "upload meta"
+	phaseUploadStorage  = "upload storage"
+	phaseWriteMetaFile  = "write meta file"
+	phaseUploadManifest = "upload manifest"
+	phaseDropSnapshot   = "drop snapshot"
+)
+
 type Backup struct {
 	ctx  context.Context
 	cfg  *config.BackupConfig
 	meta *clients.NebulaMeta
 
-	hosts *utils.NebulaHosts
-	sto   storage.ExternalStorage
+	hosts    *utils.NebulaHosts
+	agentMgr *clients.AgentManager
+	sto      storage.ExternalStorage
+
+	// only for incremental backup
+	refsMu    sync.Mutex
+	refs      *utils.BackupRefs
+	baseFiles map[string]map[string]string                      // dir -> sst file -> backup holding it
+	baseParts map[string]map[nebula.PartitionID]*nebula.LogInfo // dir -> partition logs in base checkpoint
+	parts     map[string]map[nebula.PartitionID]*nebula.LogInfo // dir -> partition logs in checkpoint
+
+	dataKey *utils.DataKey // only for encrypted backup
+
+	progress *progress.Progress
+	manifest *utils.Manifest // nil if not uploaded
 }
 
 func NewBackup(ctx context.Context, cfg *config.BackupConfig) (*Backup, error) {
 	b := &Backup{
-		ctx: context.WithValue(ctx, storage.SessionKey, uuid.NewString()),
-		cfg: cfg,
+		ctx:       context.WithValue(ctx, storage.SessionKey, uuid.NewString()),
+		cfg:       cfg,
+		baseFiles: make(map[string]map[string]string),
+		baseParts: make(map[string]map[nebula.PartitionID]*nebula.LogInfo),
+		parts:     make(map[string]map[nebula.PartitionID]*nebula.LogInfo),
 	}
 
 	var err error
-	b.meta, err = clients.NewMeta(cfg.MetaAddr)
+	b.meta, err = clients.NewMeta(cfg.MetaAddr, cfg.Conn)
 	if err != nil {
 		return nil, fmt.Errorf("create meta client failed: %w", err)
 	}
@@ -55,6 +84,14 @@ func NewBackup(ctx context.Context, cfg *config.BackupConfig) (*Backup, error) {
 	if err != nil {
 		return nil, fmt.Errorf("parse cluster response failed: %w", err)
 	}
+	b.agentMgr = clients.NewAgentManager(b.ctx, b.hosts, cfg.Conn)
+
+	if cfg.EncryptionKey != nil {
+		b.dataKey, err = cfg.EncryptionKey.NewDataKey()
+		if err != nil {
+			return nil, fmt.Errorf("generate data key failed: %w", err)
+		}
+	}
 	return b, nil
 }
 
@@ -63,13 +100,9 @@ func NewBackup(ctx context.Context, cfg *config.BackupConfig) (*Backup, error) {
 // targetUri is external storage's uri, which is meta's root dir,
 // has pattern like local://xxx, s3://xxx
 func (b *Backup) uploadMeta(host *nebula.HostAddr, targetUri string, localDir string) error {
-	agentAddr, err := b.hosts.GetAgentFor(b.meta.LeaderAddr())
-	if err != nil {
-		return err
-	}
-	agent, err := clients.NewAgent(b.ctx, agentAddr)
+	agent, err := b.agentMgr.GetAgentFor(b.meta.LeaderAddr())
 	if err != nil {
-		return fmt.Errorf("create agent failed: %w", err)
+		return fmt.Errorf("get agent failed: %w", err)
 	}
 
 	backend, err := b.sto.GetDir(b.ctx, targetUri)
@@ -88,47 +121,107 @@ func (b *Backup) uploadMeta(host *nebula.HostAddr, targetUri string, localDir st
 	return nil
 }
 
+type uploadTask struct {
+	addr    *nebula.HostAddr
+	spaceId string
+	index   int    // data path index
+	source  string // checkpoint dir in host
+}
+
+// uploadStorage uploads checkpoints in all storaged hosts concurrently, at most
+// HostConcurrency checkpoints are uploaded at the same time in one host.
 func (b *Backup) uploadStorage(hostDirs map[string]map[string][]string, targetUri string) error {
+	// interleave the tasks of different hosts, so that hosts could upload concurrently
+	hostTasks := make([][]*uploadTask, 0, len(hostDirs))
+	hostSems := make(map[string]chan struct{})
+	maxTasks := 0
 	for addrStr, spaceDirs := range hostDirs {
-		// get storage node's agent
 		addr, err := utils.ParseAddr(addrStr)
 		if err != nil {
 			return err
 		}
-		agentAddr, err := b.hosts.GetAgentFor(addr)
-		if err != nil {
-			return err
-		}
-		agent, err := clients.NewAgent(b.ctx, agentAddr)
-		if err != nil {
-			return err
-		}
 
-		logger := log.WithField("host", addrStr)
-		// upload every space in this node
+		var tasks []*uploadTask
 		for idStr, dirs := range spaceDirs {
 			for i, source := range dirs {
-				// {backupRoot}/{backupName}/data/{addr}/data{0..n}/{spaceId}
-				target, _ := utils.UriJoin(targetUri, addrStr, fmt.Sprintf("data%d", i), idStr)
-				backend, err := b.sto.GetDir(b.ctx, target)
-				if err != nil {
-					return fmt.Errorf("get storage backend for %s failed: %w", target, err)
-				}
+				tasks = append(tasks, &uploadTask{addr, idStr, i, source})
+			}
+		}
+		hostTasks = append(hostTasks, tasks)
+		hostSems[addrStr] = make(chan struct{}, b.cfg.HostConcurrency)
+		if len(tasks) > maxTasks {
+			maxTasks = len(tasks)
+		}
+	}
 
-				req := &pb.UploadFileRequest{
-					SourcePath:    source,
-					TargetBackend: backend,
-					Recursively:   true,
+	total := 0
+	for _, tasks := range hostTasks {
+		total += len(tasks)
+	}
+	// the sizes of checkpoints are unknown, since the agent uploads them directly
+	b.progress.Total(total, 0)
+
+	pool := utils.NewWorkerPool(b.ctx, b.cfg.Concurrency).ContinueOnError()
+	for i := 0; i < maxTasks; i++ {
+		for _, tasks := range hostTasks {
+			if i >= len(tasks) {
+				continue
+			}
+			t := tasks[i]
+			name := fmt.Sprintf("storaged[%s] space %s data%d", utils.StringifyAddr(t.addr), t.spaceId, t.index)
+			sem := hostSems[utils.StringifyAddr(t.addr)]
+			pool.Go(name, func(ctx context.Context) error {
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					return ctx.Err()
 				}
-				_, err = agent.UploadFile(req)
-				if err != nil {
-					return fmt.Errorf("upload %s to %s failed:%w", source, target, err)
+				defer func() { <-sem }()
+
+				if err := b.uploadCheckpoint(t, targetUri); err != nil {
+					return &utils.TaskError{Host: utils.StringifyAddr(t.addr), Path: t.source, Err: err}
 				}
-				logger.WithField("src", source).WithField("target", target).Info("Upload storage checkpoint successfully.")
-			}
+				b.progress.Done(name, 0)
+				return nil
+			})
 		}
 	}
 
+	return pool.Wait()
+}
+
+func (b *Backup) uploadCheckpoint(t *uploadTask, targetUri string) error {
+	addrStr := utils.StringifyAddr(t.addr)
+	agent, err := b.agentMgr.GetAgentFor(t.addr)
+	if err != nil {
+		return err
+	}
+
+	if b.refs != nil {
+		dir := checkpointDir(t.addr, t.index, t.spaceId)
+		if err := b.skipInherited(agent, dir, t.source); err != nil {
+			return err
+		}
+	}
+
+	// {backupRoot}/{backupName}/data/{addr}/data{0..n}/{spaceId}
+	target, _ := utils.UriJoin(targetUri, addrStr, fmt.Sprintf("data%d", t.index), t.spaceId)
+	backend, err := b.sto.GetDir(b.ctx, target)
+	if err != nil {
+		return fmt.Errorf("get storage backend for %s failed: %w", target, err)
+	}
+
+	req := &pb.UploadFileRequest{
+		SourcePath:    t.source,
+		TargetBackend: backend,
+		Recursively:   true,
+	}
+	_, err = agent.UploadFile(req)
+	if err != nil {
+		return fmt.Errorf("upload %s to %s failed:%w", t.source, target, err)
+	}
+	log.WithField("host", addrStr).WithField("src", t.source).WithField("target", target).
+		Info("Upload storage checkpoint successfully.")
 	return nil
 }
 
@@ -142,12 +235,85 @@ func (b *Backup) generateMetaFile(meta *meta.BackupMeta) (string, error) {
 	}
 	meta.MetaFiles = fileNames
 
-	return tmpMetaPath, utils.DumpMetaToFile(meta, tmpMetaPath)
+	if err := utils.DumpMetaToFile(meta, tmpMetaPath); err != nil {
+		return tmpMetaPath, err
+	}
+	if b.dataKey != nil {
+		return tmpMetaPath, utils.EncryptFile(b.dataKey, tmpMetaPath)
+	}
+	return tmpMetaPath, nil
+}
+
+func (b *Backup) uploadRefs(rootUri string, backupName string) error {
+	tmpRefsPath := filepath.Join(utils.LocalTmpDir, utils.RefsName(backupName))
+	if err := utils.DumpRefsToFile(b.refs, tmpRefsPath); err != nil {
+		return err
+	}
+	if b.dataKey != nil {
+		if err := utils.EncryptFile(b.dataKey, tmpRefsPath); err != nil {
+			return err
+		}
+	}
+
+	refsUri, _ := utils.UriJoin(rootUri, utils.RefsName(backupName))
+	if err := b.sto.Upload(b.ctx, refsUri, tmpRefsPath, false); err != nil {
+		return fmt.Errorf("upload local tmp file to remote storage %s failed: %w", refsUri, err)
+	}
+	return nil
+}
+
+func (b *Backup) phases() []string {
+	var phases []string
+	if b.cfg.BaseBackupName != "" {
+		phases = append(phases, phaseLoadBase)
+	}
+	phases = append(phases, phaseCreateSnapshot, phaseUploadMeta, phaseUploadStorage, phaseWriteMetaFile)
+	if b.cfg.Manifest {
+		phases = append(phases, phaseUploadManifest)
+	}
+	return append(phases, phaseDropSnapshot)
+}
+
+// Report fills the report with the hosts, phases and transferred files of backup,
+// the files are only known if the manifest is uploaded
+func (b *Backup) Report(rep *report.Report) {
+	rep.Hosts = b.hosts.Hosts()
+	rep.Phases = b.progress.Records()
+	if b.manifest == nil {
+		rep.SizeUnknown = "unknown, no manifest"
+		return
+	}
+	var bytes int64
+	for _, f := range b.manifest.Files {
+		bytes += f.Size
+	}
+	rep.SetSize(len(b.manifest.Files), bytes)
 }
 
 // Backup backs up data in given external storage, and return the backup name
 func (b *Backup) Backup() (string, error) {
+	b.progress = progress.New("backup", b.phases())
+	backupName, err := b.backup()
+	b.progress.Finish(err)
+	return backupName, err
+}
+
+func (b *Backup) backup() (string, error) {
+	if b.dataKey != nil {
+		// sst files are uploaded by agents from cluster hosts to external storage directly
+		log.WithField("key id", b.dataKey.KeyID()).
+			Warn("Only the backup meta files are encrypted by br, the data and meta sst files are uploaded as they are, " +
+				"please enable the server side encryption of external storage for them.")
+	}
+	if b.cfg.BaseBackupName != "" {
+		b.progress.Phase(phaseLoadBase)
+		if err := b.loadBase(); err != nil {
+			return "", fmt.Errorf("load base backup %s failed: %w", b.cfg.BaseBackupName, err)
+		}
+	}
+
 	// call the meta service, create backup files in each local
+	b.progress.Phase(phaseCreateSnapshot)
 	backupRes, err := b.meta.CreateBackup(b.cfg.Spaces)
 	if err != nil {
 		if backupRes != nil && backupRes.GetMeta() != nil && backupRes.GetMeta().GetBackupName() != nil {
@@ -180,6 +346,7 @@ func (b *Backup) Backup() (string, error) {
 		return backupName, fmt.Errorf("there is no meta files in backup info")
 	}
 	localMetaDir := path.Dir(string(backupInfo.MetaFiles[0]))
+	b.progress.Phase(phaseUploadMeta)
 	if err = b.uploadMeta(b.meta.LeaderAddr(), metaDir, localMetaDir); err != nil {
 		return backupName, err
 	}
@@ -193,15 +360,17 @@ func (b *Backup) Backup() (string, error) {
 		idStr := strconv.FormatInt(int64(sid), 10)
 		for _, hb := range sb.GetHostBackups() {
 			hostStr := utils.StringifyAddr(hb.GetHost())
-			for _, cp := range hb.GetCheckpoints() {
+			for i, cp := range hb.GetCheckpoints() {
 				if _, ok := hostDirs[hostStr]; !ok {
 					hostDirs[hostStr] = make(map[string][]string)
 				}
+				b.parts[checkpointDir(hb.GetHost(), i, idStr)] = cp.GetParts()
 
 				hostDirs[hostStr][idStr] = append(hostDirs[hostStr][idStr], string(cp.GetDataPath()))
 			}
 		}
 	}
+	b.progress.Phase(phaseUploadStorage)
 	err = b.uploadStorage(hostDirs, storageDir)
 	if err != nil {
 		return backupName, fmt.Errorf("upload storage failed %w", err)
@@ -209,6 +378,7 @@ func (b *Backup) Backup() (string, error) {
 	logger.WithField("data", storageDir).Info("Upload data backup successfully.")
 
 	// generate backup meta files and upload
+	b.progress.Phase(phaseWriteMetaFile)
 	if err := utils.EnsureDir(utils.LocalTmpDir); err != nil {
 		return backupName, err
 	}
@@ -218,6 +388,17 @@ func (b *Backup) Backup() (string, error) {
 		}
 	}()
 
+	if b.refs != nil {
+		err = b.uploadRefs(rootUri, backupName)
+		if err != nil {
+			return backupName, fmt.Errorf("upload refs of incremental backup failed: %w", err)
+		}
+		logger.WithField("base", b.refs.Base).Info("Upload incremental backup refs successfully.")
+
+		backupInfo.Full = false
+		backupInfo.BaseBackupName = []byte(b.refs.Base)
+	}
+
 	tmpMetaPath, err := b.generateMetaFile(backupInfo)
 	if err != nil {
 		return backupName, fmt.Errorf("write meta to tmp path failed: %w", err)
@@ -231,9 +412,20 @@ func (b *Backup) Backup() (string, error) {
 	if err != nil {
 		return backupName, fmt.Errorf("upload local tmp file to remote storage %s failed: %w", backupMetaPath, err)
 	}
-	logger.WithField("remote path", backupMetaPath).Info("Upload tmp backup meta file to remote.")
+	logger.WithField("remote path", backupMetaPath).WithField("encrypted", b.dataKey != nil).
+		Info("Upload tmp backup meta file to remote.")
+
+	// record all the files with their checksums, for verifying without cluster
+	if b.cfg.Manifest {
+		b.progress.Phase(phaseUploadManifest)
+		err = b.uploadManifest(rootUri, backupName, hostDirs)
+		if err != nil {
+			return backupName, fmt.Errorf("upload backup manifest failed: %w", err)
+		}
+	}
 
 	// drop backup files in cluster machine local and local tmp files
+	b.progress.Phase(phaseDropSnapshot)
 	err = b.meta.DropBackup(backupInfo.GetBackupName())
 	if err != nil {
 		return backupName, fmt.Errorf("drop backup %s in cluster local failed: %w",
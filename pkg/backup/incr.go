@@ -0,0 +1,159 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+
+	pb "github.com/vesoft-inc/nebula-agent/pkg/proto"
+
+	"github.com/vesoft-inc/nebula-br/pkg/clients"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+	"github.com/vesoft-inc/nebula-go/v3/nebula"
+)
+
+const (
+	// rocksdb checkpoint folder in storage checkpoint dir
+	checkpointDataDir = "data"
+	sstSuffix         = ".sst"
+	// concurrent agent calls to skip the inherited files of one checkpoint
+	skipConcurrency = 16
+)
+
+// loadBase collects all the sst files in the base backup chain, which could be
+// referenced by the incremental backup instead of uploading again.
+func (b *Backup) loadBase() error {
+	base := b.cfg.BaseBackupName
+	rootUri := b.cfg.Backend.Uri()
+	logger := log.WithField("base", base)
+
+	baseMeta, err := utils.DownloadMetaFile(b.ctx, b.sto, rootUri, base, b.cfg.EncryptionKey)
+	if err != nil {
+		return fmt.Errorf("get backup meta of %s failed: %w", base, err)
+	}
+
+	b.refs = utils.NewBackupRefs(base)
+	// sst files referenced by the base are also accessible for us
+	if len(baseMeta.GetBaseBackupName()) != 0 {
+		baseRefs, err := utils.DownloadRefs(b.ctx, b.sto, rootUri, base, b.cfg.EncryptionKey)
+		if err != nil {
+			return fmt.Errorf("get refs of incremental backup %s failed: %w", base, err)
+		}
+		b.refs.Chain = append(b.refs.Chain, baseRefs.Chain...)
+		for dir, files := range baseRefs.Files {
+			for f, owner := range files {
+				b.addBaseFile(dir, f, owner)
+			}
+		}
+	}
+
+	// sst files uploaded by the base itself
+	dataUri, _ := utils.UriJoin(rootUri, base, "data")
+	for sid, sb := range baseMeta.GetSpaceBackups() {
+		idStr := strconv.FormatInt(int64(sid), 10)
+		for _, hb := range sb.GetHostBackups() {
+			for i, cp := range hb.GetCheckpoints() {
+				dir := checkpointDir(hb.GetHost(), i, idStr)
+				b.baseParts[dir] = cp.GetParts()
+				uri, _ := utils.UriJoin(dataUri, dir)
+				names, err := b.sto.ListDir(b.ctx, uri)
+				if err != nil {
+					return fmt.Errorf("list %s failed: %w", uri, err)
+				}
+				for _, name := range names {
+					name = strings.Trim(name, "/")
+					if strings.HasSuffix(name, sstSuffix) {
+						b.addBaseFile(dir, name, base)
+					}
+				}
+			}
+		}
+	}
+
+	logger.WithField("chain", b.refs.Chain).Info("Load base backup successfully.")
+	return nil
+}
+
+func (b *Backup) addBaseFile(dir, file, owner string) {
+	if _, ok := b.baseFiles[dir]; !ok {
+		b.baseFiles[dir] = make(map[string]string)
+	}
+	b.baseFiles[dir][file] = owner
+}
+
+// checkpointDir returns the dir of the i-th checkpoint of the storaged in space, relative to the backup data dir,
+// it is {addr}/data{0..n}/{spaceId}/data
+func checkpointDir(addr *nebula.HostAddr, i int, spaceId string) string {
+	return path.Join(utils.StringifyAddr(addr), fmt.Sprintf("data%d", i), spaceId, checkpointDataDir)
+}
+
+// rewound returns a partition whose commit log in the checkpoint is behind the base backup. The sst files are
+// immutable and named by an increasing number in one rocksdb, but the number goes back with the data, e.g.
+// the space is restored from an older backup, so that a file with the same name may have different content.
+func rewound(base, curr map[nebula.PartitionID]*nebula.LogInfo) (nebula.PartitionID, bool) {
+	for pid, baseLog := range base {
+		// the partition may be moved to other hosts by balance
+		currLog, ok := curr[pid]
+		if !ok {
+			continue
+		}
+		if currLog.GetCommitLogID() < baseLog.GetCommitLogID() {
+			return pid, true
+		}
+	}
+	return 0, false
+}
+
+// skipInherited removes the sst files which already exist in the base chain from
+// the local checkpoint, so that the agent will not upload them again.
+// Files in checkpoint are hard links, removing them will not affect the online data.
+func (b *Backup) skipInherited(agent *clients.NebulaAgent, dir string, source string) error {
+	files, ok := b.baseFiles[dir]
+	if !ok {
+		return nil
+	}
+	logger := log.WithField("dir", dir)
+	if pid, ok := rewound(b.baseParts[dir], b.parts[dir]); ok {
+		logger.WithField("part", pid).Warn("Data is behind the base backup, upload all the sst files in it.")
+		return nil
+	}
+
+	// the agent could only check and remove one path a time, so they are sent concurrently
+	var skipped int32
+	pool := utils.NewWorkerPool(b.ctx, skipConcurrency)
+	for f, owner := range files {
+		f, owner := f, owner
+		localPath := filepath.Join(source, checkpointDataDir, f)
+		pool.Go(localPath, func(ctx context.Context) error {
+			res, err := agent.ExistDir(&pb.ExistDirRequest{Path: localPath})
+			if err != nil {
+				return fmt.Errorf("check %s exist failed: %w", localPath, err)
+			}
+			if !res.Exist {
+				return nil
+			}
+
+			_, err = agent.RemoveDir(&pb.RemoveDirRequest{Path: localPath})
+			if err != nil {
+				return fmt.Errorf("remove inherited file %s from checkpoint failed: %w", localPath, err)
+			}
+			b.refsMu.Lock()
+			b.refs.Add(dir, f, owner)
+			b.refsMu.Unlock()
+			atomic.AddInt32(&skipped, 1)
+			return nil
+		})
+	}
+	if err := pool.Wait(); err != nil {
+		return err
+	}
+
+	logger.WithField("skipped", skipped).Info("Skip sst files existing in base backup.")
+	return nil
+}
@@ -0,0 +1,41 @@
+package backup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vesoft-inc/nebula-go/v3/nebula"
+)
+
+func TestRewound(t *testing.T) {
+	assert := assert.New(t)
+
+	logs := func(commits ...nebula.LogID) map[nebula.PartitionID]*nebula.LogInfo {
+		m := make(map[nebula.PartitionID]*nebula.LogInfo)
+		for i, c := range commits {
+			m[nebula.PartitionID(i+1)] = &nebula.LogInfo{CommitLogID: c}
+		}
+		return m
+	}
+
+	_, ok := rewound(logs(10, 20), logs(10, 25))
+	assert.False(ok)
+	_, ok = rewound(nil, logs(10))
+	assert.False(ok)
+
+	// the partition moved away is not compared
+	_, ok = rewound(logs(10, 20), logs(15))
+	assert.False(ok)
+
+	pid, ok := rewound(logs(10, 20), logs(12, 5))
+	assert.True(ok)
+	assert.Equal(nebula.PartitionID(2), pid)
+}
+
+func TestCheckpointDir(t *testing.T) {
+	assert := assert.New(t)
+
+	addr := &nebula.HostAddr{Host: "192.168.0.1", Port: 9779}
+	assert.Equal("192.168.0.1:9779/data1/3/data", checkpointDir(addr, 1, "3"))
+}
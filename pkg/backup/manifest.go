@@ -0,0 +1,63 @@
+package backup
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+)
+
+// uploadManifest downloads all the uploaded files back to compute their checksums,
+// and upload the manifest as {backupRoot}/{backupName}/{backupName}.manifest
+func (b *Backup) uploadManifest(rootUri, backupName string, hostDirs map[string]map[string][]string) error {
+	m := &utils.Manifest{
+		BackupName: backupName,
+		Dirs:       []string{"meta"},
+	}
+	for addrStr, spaceDirs := range hostDirs {
+		for idStr, dirs := range spaceDirs {
+			for i := range dirs {
+				m.Dirs = append(m.Dirs, path.Join("data", addrStr, fmt.Sprintf("data%d", i), idStr))
+			}
+		}
+	}
+	sort.Strings(m.Dirs)
+
+	for _, dir := range m.Dirs {
+		files, err := utils.ChecksumDir(b.ctx, b.sto, rootUri, dir)
+		if err != nil {
+			return fmt.Errorf("compute checksum of %s failed: %w", dir, err)
+		}
+		m.Files = append(m.Files, files...)
+		log.WithField("dir", dir).WithField("files", len(files)).Debug("Compute checksum of backup dir.")
+	}
+
+	singleFiles := []string{fmt.Sprintf("%s.meta", backupName)}
+	if b.refs != nil {
+		singleFiles = append(singleFiles, utils.RefsName(backupName))
+	}
+	for _, file := range singleFiles {
+		f, err := utils.ChecksumFile(b.ctx, b.sto, rootUri, file)
+		if err != nil {
+			return fmt.Errorf("compute checksum of %s failed: %w", file, err)
+		}
+		m.Files = append(m.Files, f)
+	}
+
+	tmpManifestPath := filepath.Join(utils.LocalTmpDir, utils.ManifestName(backupName))
+	if err := utils.DumpManifestToFile(m, tmpManifestPath); err != nil {
+		return err
+	}
+	manifestUri, _ := utils.UriJoin(rootUri, utils.ManifestName(backupName))
+	if err := b.sto.Upload(b.ctx, manifestUri, tmpManifestPath, false); err != nil {
+		return fmt.Errorf("upload local tmp file to remote storage %s failed: %w", manifestUri, err)
+	}
+
+	log.WithField("files", len(m.Files)).WithField("remote path", manifestUri).Info("Upload backup manifest successfully.")
+	b.manifest = m
+	return nil
+}
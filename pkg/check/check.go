@@ -0,0 +1,448 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+
+	pb "github.com/vesoft-inc/nebula-agent/pkg/proto"
+	"github.com/vesoft-inc/nebula-agent/pkg/storage"
+	"github.com/vesoft-inc/nebula-br/pkg/clients"
+	"github.com/vesoft-inc/nebula-br/pkg/config"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+	"github.com/vesoft-inc/nebula-go/v3/nebula"
+	"github.com/vesoft-inc/nebula-go/v3/nebula/meta"
+)
+
+const probeName = "probe"
+
+// Result is the result of checking an item of a target, e.g. the agent of a host
+type Result struct {
+	Item   string
+	Target string
+	Passed bool
+	Detail string
+	Hint   string // how to fix, only for failed ones
+}
+
+// Check checks whether the cluster and external storage are ready for backup and restore,
+// all the problems are reported instead of stopping at the first one.
+type Check struct {
+	ctx context.Context
+	cfg *config.CheckConfig
+	id  string // to name the probe files
+
+	sto      storage.ExternalStorage
+	meta     *clients.NebulaMeta
+	hosts    *utils.NebulaHosts
+	agentMgr *clients.AgentManager
+	agents   map[string]*clients.NebulaAgent // host -> the reachable agent
+	probeUri string                          // empty if the external storage is not writable
+
+	results []*Result
+}
+
+func NewCheck(ctx context.Context, cfg *config.CheckConfig) *Check {
+	return &Check{
+		ctx:    ctx,
+		cfg:    cfg,
+		id:     uuid.NewString(),
+		agents: make(map[string]*clients.NebulaAgent),
+	}
+}
+
+func (c *Check) pass(item, target, detail string) {
+	c.results = append(c.results, &Result{Item: item, Target: target, Passed: true, Detail: detail})
+}
+
+func (c *Check) fail(item, target, detail, hint string) {
+	log.WithField("item", item).WithField("target", target).WithField("detail", detail).Error("Check failed.")
+	c.results = append(c.results, &Result{Item: item, Target: target, Detail: detail, Hint: hint})
+}
+
+// Check runs all the checks, the checks of cluster depend on the former ones
+func (c *Check) Check() error {
+	c.checkStorage()
+	if c.checkMeta() {
+		c.checkVersions()
+		if c.checkTopology() {
+			c.checkAgents()
+			c.checkDirs()
+			c.checkAgentStorage()
+		}
+	}
+	c.cleanProbe()
+
+	c.showResults()
+	if err := summarize(c.results); err != nil {
+		return err
+	}
+	fmt.Printf("All %d checks passed.\n", len(c.results))
+	return nil
+}
+
+// summarize returns an error with the count of failed results, nil if all passed
+func summarize(results []*Result) error {
+	failed := 0
+	for _, r := range results {
+		if !r.Passed {
+			failed++
+		}
+	}
+	if failed != 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(results))
+	}
+	return nil
+}
+
+// checkStorage writes a probe file to the external storage, which is downloaded by agents later
+func (c *Check) checkStorage() {
+	const item = "external storage"
+	rootUri := c.cfg.Backend.Uri()
+	hint := "check the --storage uri, the s3 options and the network to the storage"
+
+	sto, err := storage.New(c.cfg.Backend)
+	if err != nil {
+		c.fail(item, rootUri, fmt.Sprintf("create storage failed: %v", err), hint)
+		return
+	}
+	c.sto = sto
+
+	probeUri, err := utils.UriJoin(rootUri, ".br_check_"+c.id)
+	if err != nil {
+		c.fail(item, rootUri, err.Error(), hint)
+		return
+	}
+	if err := utils.EnsureDir(utils.LocalTmpDir); err != nil {
+		c.fail(item, rootUri, err.Error(), "check the permission of current dir")
+		return
+	}
+	localPath := filepath.Join(utils.LocalTmpDir, "br_check_"+c.id)
+	defer os.Remove(localPath)
+	if err := ioutil.WriteFile(localPath, []byte(c.id), 0644); err != nil {
+		c.fail(item, rootUri, err.Error(), "check the permission of current dir")
+		return
+	}
+
+	fileUri, _ := utils.UriJoin(probeUri, probeName)
+	if err := c.sto.Upload(c.ctx, fileUri, localPath, false); err != nil {
+		c.fail(item, rootUri, fmt.Sprintf("write probe file failed: %v", err), hint+", and the write permission")
+		return
+	}
+	c.probeUri = probeUri
+	c.pass(item, rootUri, "writable")
+}
+
+func (c *Check) cleanProbe() {
+	if c.probeUri == "" {
+		return
+	}
+	if err := c.sto.RemoveDir(c.ctx, c.probeUri); err != nil {
+		log.WithError(err).WithField("uri", c.probeUri).Warn("Remove probe files in external storage failed.")
+	}
+}
+
+// checkMeta connects the meta service, the client version is verified when connecting
+func (c *Check) checkMeta() bool {
+	const item = "meta service"
+	client, err := clients.NewMeta(c.cfg.MetaAddr, c.cfg.Conn)
+	if err != nil {
+		c.fail(item, c.cfg.MetaAddr, err.Error(),
+			"check the --meta addresses, the network and the TLS options, and use the br matching the cluster version")
+		return false
+	}
+	c.meta = client
+	c.pass(item, utils.StringifyAddr(client.LeaderAddr()), "connected, client version "+nebula.Version)
+	return true
+}
+
+func majorVersion(v string) string {
+	return strings.SplitN(strings.TrimPrefix(v, "v"), ".", 2)[0]
+}
+
+// checkVersions checks all the services are online and their versions are compatible with br
+func (c *Check) checkVersions() {
+	const item = "service version"
+	for _, typ := range []meta.ListHostType{meta.ListHostType_META, meta.ListHostType_STORAGE, meta.ListHostType_GRAPH} {
+		items, err := c.meta.ListHosts(typ)
+		if err != nil {
+			c.fail(item, typ.String(), fmt.Sprintf("list hosts failed: %v", err), "check the meta service")
+			continue
+		}
+		for _, h := range items {
+			target := fmt.Sprintf("%s[%s]", typ.String(), utils.StringifyAddr(h.GetHostAddr()))
+			version := string(h.GetVersion())
+			if h.GetStatus() != meta.HostStatus_ONLINE {
+				c.fail(item, target, "service is "+h.GetStatus().String(), "start the service or remove it from the cluster")
+				continue
+			}
+			if version == "" {
+				c.pass(item, target, "online, version unknown")
+				continue
+			}
+			if majorVersion(version) != majorVersion(nebula.Version) {
+				c.fail(item, target, fmt.Sprintf("version %s is incompatible with client version %s", version, nebula.Version),
+					"use the br release matching the cluster version")
+				continue
+			}
+			c.pass(item, target, "online, version "+version)
+		}
+	}
+}
+
+// checkTopology checks there is exactly one agent in each host, the hosts with more agents
+// are left out of the later checks, so that the other hosts are still checked.
+func (c *Check) checkTopology() bool {
+	const item = "cluster topology"
+	resp, err := c.meta.ListCluster()
+	if err != nil {
+		c.fail(item, c.cfg.MetaAddr, err.Error(), "check the meta service")
+		return false
+	}
+
+	results, hostServices := checkAgentCount(resp.GetHostServices())
+	for _, r := range results {
+		if !r.Passed {
+			log.WithField("item", r.Item).WithField("target", r.Target).WithField("detail", r.Detail).Error("Check failed.")
+		}
+	}
+	c.results = append(c.results, results...)
+
+	checked := *resp
+	checked.HostServices = hostServices
+	c.hosts = &utils.NebulaHosts{}
+	if err := c.hosts.LoadFrom(&checked); err != nil {
+		c.fail(item, c.cfg.MetaAddr, fmt.Sprintf("parse cluster topology failed: %v", err), "check the meta service")
+		return false
+	}
+	c.agentMgr = clients.NewAgentManager(c.ctx, c.hosts, c.cfg.Conn)
+	return true
+}
+
+// checkAgentCount checks there is exactly one agent in each host, and returns the results
+// sorted by host, with the services in the hosts which have no more than one agent
+func checkAgentCount(hostServices map[string][]*meta.ServiceInfo) ([]*Result, map[string][]*meta.ServiceInfo) {
+	const item = "agent"
+	hosts := make([]string, 0, len(hostServices))
+	for host := range hostServices {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	results := make([]*Result, 0, len(hosts))
+	checked := make(map[string][]*meta.ServiceInfo)
+	for _, host := range hosts {
+		var agents []string
+		for _, s := range hostServices[host] {
+			if s.GetRole() == meta.HostRole_AGENT {
+				agents = append(agents, utils.StringifyAddr(s.GetAddr()))
+			}
+		}
+		switch len(agents) {
+		case 0:
+			results = append(results, &Result{Item: item, Target: host, Detail: "no agent found in host",
+				Hint: "start nebula-agent in the host, with --meta of the cluster"})
+		case 1:
+			results = append(results, &Result{Item: item, Target: host, Passed: true, Detail: "agent " + agents[0]})
+		default:
+			results = append(results, &Result{Item: item, Target: host,
+				Detail: "more than one agent in host: " + strings.Join(agents, ", "),
+				Hint:   "keep only one nebula-agent in each host"})
+			continue
+		}
+		checked[host] = hostServices[host]
+	}
+	return results, checked
+}
+
+// rootDir returns a nebula root dir in host, empty if not found
+func (c *Check) rootDir(host string) string {
+	dirs := c.hosts.GetRootDirs()[host]
+	if len(dirs) == 0 {
+		return ""
+	}
+	return dirs[0].Dir
+}
+
+func (c *Check) existDir(agent *clients.NebulaAgent, dir string) (bool, error) {
+	resp, err := agent.ExistDir(&pb.ExistDirRequest{Path: dir})
+	if err != nil {
+		return false, err
+	}
+	return resp.Exist, nil
+}
+
+// checkAgents pings the agent in every host
+func (c *Check) checkAgents() {
+	const item = "agent connection"
+	for _, addr := range c.hosts.GetAgents() {
+		target := utils.StringifyAddr(addr)
+		agent, err := c.agentMgr.GetAgent(addr)
+		if err == nil {
+			_, err = c.existDir(agent, "/")
+		}
+		if err != nil {
+			c.fail(item, target, err.Error(), "check the agent process, its port and the TLS options")
+			continue
+		}
+		c.agents[addr.GetHost()] = agent
+		c.pass(item, target, "connected")
+	}
+}
+
+// checkDirs checks the root and data dirs of every service exist, and metad has only one data dir
+func (c *Check) checkDirs() {
+	const item = "dir layout"
+	hostServices := c.hosts.GetHostServices()
+	for _, host := range c.hosts.Hosts() {
+		agent, ok := c.agents[host]
+		if !ok {
+			continue
+		}
+		for _, s := range hostServices[host] {
+			if s.GetRole() == meta.HostRole_AGENT {
+				continue
+			}
+			target := fmt.Sprintf("%s[%s]", s.GetRole().String(), utils.StringifyAddr(s.GetAddr()))
+			if s.GetDir() == nil || len(s.GetDir().GetRoot()) == 0 {
+				c.fail(item, target, "no root dir reported", "upgrade the service to report its dirs to meta")
+				continue
+			}
+
+			dirs := []string{string(s.GetDir().GetRoot())}
+			for _, d := range s.GetDir().GetData() {
+				dirs = append(dirs, string(d))
+			}
+			switch s.GetRole() {
+			case meta.HostRole_META:
+				if len(s.GetDir().GetData()) != 1 {
+					c.fail(item, target, fmt.Sprintf("metad has %d data dirs", len(s.GetDir().GetData())),
+						"configure exactly one data_path for metad")
+					continue
+				}
+			case meta.HostRole_STORAGE:
+				if len(s.GetDir().GetData()) == 0 {
+					c.fail(item, target, "storaged has no data dir", "configure data_path for storaged")
+					continue
+				}
+			}
+
+			var missing []string
+			var err error
+			for _, d := range dirs {
+				var exist bool
+				exist, err = c.existDir(agent, d)
+				if err != nil {
+					break
+				}
+				if !exist {
+					missing = append(missing, d)
+				}
+			}
+			switch {
+			case err != nil:
+				c.fail(item, target, fmt.Sprintf("check dirs by agent failed: %v", err), "check the agent")
+			case len(missing) != 0:
+				c.fail(item, target, "dirs not found: "+strings.Join(missing, ", "),
+					"check the dirs in service config, the agent should run in the same host as the service")
+			default:
+				c.pass(item, target, fmt.Sprintf("%d dirs found", len(dirs)))
+			}
+		}
+	}
+}
+
+// checkAgentStorage lets every agent download the probe file from external storage,
+// and upload it back, which is what they do in backup and restore.
+func (c *Check) checkAgentStorage() {
+	const item = "agent storage access"
+	if c.probeUri == "" {
+		return
+	}
+	hint := "check the network and credentials from the host to the external storage"
+	if c.cfg.Backend.GetLocal() != nil {
+		hint = "local storage should be a shared filesystem mounted in all the hosts, s3 is recommended"
+	}
+
+	downloadBackend, err := c.sto.GetDir(c.ctx, c.probeUri)
+	if err != nil {
+		c.fail(item, c.probeUri, fmt.Sprintf("get storage backend failed: %v", err), hint)
+		return
+	}
+	for _, host := range c.hosts.Hosts() {
+		agent, ok := c.agents[host]
+		if !ok {
+			continue
+		}
+		target := utils.StringifyAddr(agent.GetAddr())
+		root := c.rootDir(host)
+		if root == "" {
+			c.fail(item, target, "no nebula root dir in host to put the probe file", "check the dir layout")
+			continue
+		}
+
+		localDir := filepath.Join(root, ".br_check_"+c.id)
+		if err := c.probe(agent, downloadBackend, localDir); err != nil {
+			c.fail(item, target, err.Error(), hint)
+		} else {
+			c.pass(item, target, "download and upload ok")
+		}
+		if _, err := agent.RemoveDir(&pb.RemoveDirRequest{Path: localDir}); err != nil {
+			log.WithError(err).WithField("host", host).WithField("path", localDir).Warn("Remove probe dir failed.")
+		}
+	}
+}
+
+func (c *Check) probe(agent *clients.NebulaAgent, downloadBackend *pb.Backend, localDir string) error {
+	_, err := agent.DownloadFile(&pb.DownloadFileRequest{
+		SourceBackend: downloadBackend,
+		TargetPath:    localDir,
+		Recursively:   true,
+	})
+	if err != nil {
+		return fmt.Errorf("download probe file failed: %w", err)
+	}
+
+	uploadUri, _ := utils.UriJoin(c.probeUri, agent.GetAddr().GetHost())
+	uploadBackend, err := c.sto.GetDir(c.ctx, uploadUri)
+	if err != nil {
+		return fmt.Errorf("get storage backend failed: %w", err)
+	}
+	_, err = agent.UploadFile(&pb.UploadFileRequest{
+		SourcePath:    localDir,
+		TargetBackend: uploadBackend,
+		Recursively:   true,
+	})
+	if err != nil {
+		return fmt.Errorf("upload probe file failed: %w", err)
+	}
+	return nil
+}
+
+func (c *Check) showResults() {
+	asciiTable := make([][]string, 0, len(c.results))
+	for _, r := range c.results {
+		result, detail := "pass", r.Detail
+		if !r.Passed {
+			result = "FAIL"
+			if r.Hint != "" {
+				detail = fmt.Sprintf("%s (hint: %s)", r.Detail, r.Hint)
+			}
+		}
+		asciiTable = append(asciiTable, []string{r.Item, r.Target, result, detail})
+	}
+
+	tw := tablewriter.NewWriter(os.Stdout)
+	tw.SetHeader([]string{"item", "target", "result", "detail"})
+	tw.SetAutoWrapText(false)
+	tw.AppendBulk(asciiTable)
+	tw.Render()
+}
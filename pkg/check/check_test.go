@@ -0,0 +1,73 @@
+package check
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vesoft-inc/nebula-go/v3/nebula"
+	"github.com/vesoft-inc/nebula-go/v3/nebula/meta"
+)
+
+func TestMajorVersion(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("3", majorVersion("3.3.1"))
+	assert.Equal("3", majorVersion("v3.0.0"))
+	assert.Equal("2", majorVersion("2.6.2-ent"))
+	assert.Equal("3", majorVersion("3"))
+	assert.Equal("", majorVersion(""))
+	assert.NotEqual(majorVersion("v2.6.0"), majorVersion("3.0.0"))
+}
+
+func TestSummarize(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(summarize(nil))
+	assert.Nil(summarize([]*Result{{Passed: true}, {Passed: true}}))
+
+	err := summarize([]*Result{{Passed: true}, {Passed: false}, {Passed: false}})
+	assert.NotNil(err)
+	assert.Equal("2 of 3 checks failed", err.Error())
+}
+
+func TestCheckAgentCount(t *testing.T) {
+	assert := assert.New(t)
+
+	service := func(role meta.HostRole, host string, port int32) *meta.ServiceInfo {
+		return &meta.ServiceInfo{Role: role, Addr: &nebula.HostAddr{Host: host, Port: port}}
+	}
+	hostServices := map[string][]*meta.ServiceInfo{
+		"192.168.0.3": {
+			service(meta.HostRole_STORAGE, "192.168.0.3", 9779),
+			service(meta.HostRole_AGENT, "192.168.0.3", 8888),
+			service(meta.HostRole_AGENT, "192.168.0.3", 8889),
+		},
+		"192.168.0.1": {
+			service(meta.HostRole_META, "192.168.0.1", 9559),
+			service(meta.HostRole_AGENT, "192.168.0.1", 8888),
+		},
+		"192.168.0.2": {
+			service(meta.HostRole_GRAPH, "192.168.0.2", 9669),
+		},
+	}
+
+	results, checked := checkAgentCount(hostServices)
+	assert.Len(results, 3)
+	assert.Equal("192.168.0.1", results[0].Target)
+	assert.True(results[0].Passed)
+	assert.Equal("agent 192.168.0.1:8888", results[0].Detail)
+	assert.Equal("192.168.0.2", results[1].Target)
+	assert.False(results[1].Passed)
+	assert.NotEmpty(results[1].Hint)
+	assert.Equal("192.168.0.3", results[2].Target)
+	assert.False(results[2].Passed)
+	assert.Contains(results[2].Detail, "192.168.0.3:8889")
+
+	// the host with more than one agent is left out, the others are still checked
+	assert.Len(checked, 2)
+	assert.Contains(checked, "192.168.0.1")
+	assert.Contains(checked, "192.168.0.2")
+	assert.NotContains(checked, "192.168.0.3")
+	assert.NotNil(summarize(results))
+}
@@ -3,6 +3,7 @@ package cleanup
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/vesoft-inc/nebula-br/pkg/clients"
 	"github.com/vesoft-inc/nebula-br/pkg/config"
+	"github.com/vesoft-inc/nebula-br/pkg/report"
 	"github.com/vesoft-inc/nebula-br/pkg/utils"
 )
 
@@ -25,13 +27,27 @@ type Cleanup struct {
 	agentMgr *clients.AgentManager
 }
 
-func NewCleanup(ctx context.Context, cfg *config.CleanupConfig) (*Cleanup, error) {
+// newExternalCleanup only cleans up the backups in external storage, which is not local,
+// so that the cluster is not needed
+func newExternalCleanup(ctx context.Context, cfg *config.CleanupConfig) (*Cleanup, error) {
 	sto, err := storage.New(cfg.Backend)
 	if err != nil {
 		return nil, fmt.Errorf("create storage for %s failed: %w", cfg.Backend.Uri(), err)
 	}
+	return &Cleanup{
+		ctx: ctx,
+		cfg: cfg,
+		sto: sto,
+	}, nil
+}
+
+func NewCleanup(ctx context.Context, cfg *config.CleanupConfig) (*Cleanup, error) {
+	c, err := newExternalCleanup(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	client, err := clients.NewMeta(cfg.MetaAddr)
+	client, err := clients.NewMeta(cfg.MetaAddr, cfg.Conn)
 	if err != nil {
 		return nil, fmt.Errorf("create meta client failed: %w", err)
 	}
@@ -46,14 +62,75 @@ func NewCleanup(ctx context.Context, cfg *config.CleanupConfig) (*Cleanup, error
 		return nil, fmt.Errorf("parse cluster response failed: %w", err)
 	}
 
-	return &Cleanup{
-		ctx:      ctx,
-		cfg:      cfg,
-		client:   client,
-		sto:      sto,
-		hosts:    hosts,
-		agentMgr: clients.NewAgentManager(ctx, hosts),
-	}, nil
+	c.client = client
+	c.hosts = hosts
+	c.agentMgr = clients.NewAgentManager(ctx, hosts, cfg.Conn)
+	return c, nil
+}
+
+// referrers returns the incremental backups under the storage root which are based on the backup,
+// directly or not, their refs point to the sst files in it.
+func (c *Cleanup) referrers(backupName string) ([]string, error) {
+	rootUri := c.cfg.Backend.Uri()
+	dirNames, err := c.sto.ListDir(c.ctx, rootUri)
+	if err != nil {
+		return nil, fmt.Errorf("list dir %s failed: %w", rootUri, err)
+	}
+
+	var names []string
+	for _, name := range dirNames {
+		name = strings.Trim(name, "/") // the s3 list result may have slashes
+		if !utils.IsBackupName(name) || name == backupName {
+			continue
+		}
+
+		m, err := utils.DownloadMetaFile(c.ctx, c.sto, rootUri, name, c.cfg.EncryptionKey)
+		if err != nil {
+			log.WithError(err).WithField("backup", name).Warn("Get backup meta file failed, skip it.")
+			continue
+		}
+		if len(m.GetBaseBackupName()) == 0 {
+			continue
+		}
+		refs, err := utils.DownloadRefs(c.ctx, c.sto, rootUri, name, c.cfg.EncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("get refs of incremental backup %s failed: %w", name, err)
+		}
+		for _, base := range refs.Chain {
+			if base == backupName {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// checkReferred refuses to clean up a backup which other incremental backups are based on, unless forced
+func (c *Cleanup) checkReferred() error {
+	if err := utils.EnsureDir(utils.LocalTmpDir); err != nil {
+		return err
+	}
+	defer func() {
+		if err := utils.RemoveDir(utils.LocalTmpDir); err != nil {
+			log.WithError(err).Errorf("Remove tmp dir %s failed.", utils.LocalTmpDir)
+		}
+	}()
+
+	names, err := c.referrers(c.cfg.BackupName)
+	if err != nil {
+		return fmt.Errorf("find incremental backups based on %s failed: %w", c.cfg.BackupName, err)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	if !c.cfg.Force {
+		return fmt.Errorf("backup %s is the base of incremental backups %s, clean them up first, "+
+			"or specify --force to break them", c.cfg.BackupName, strings.Join(names, ","))
+	}
+	log.WithField("backups", names).Warn("Clean up the base of incremental backups by force, they could not be restored any more.")
+	return nil
 }
 
 func (c *Cleanup) cleanNebula() error {
@@ -66,8 +143,8 @@ func (c *Cleanup) cleanNebula() error {
 	return nil
 }
 
-func (c *Cleanup) cleanExternal() error {
-	backupUri, err := utils.UriJoin(c.cfg.Backend.Uri(), c.cfg.BackupName)
+func (c *Cleanup) cleanExternal(backupName string) error {
+	backupUri, err := utils.UriJoin(c.cfg.Backend.Uri(), backupName)
 	if err != nil {
 		return err
 	}
@@ -82,7 +159,7 @@ func (c *Cleanup) cleanExternal() error {
 	// which should be handled separately
 	if c.cfg.Backend.GetLocal() != nil {
 		for _, addr := range c.hosts.GetAgents() {
-			agent, err := clients.NewAgent(c.ctx, addr)
+			agent, err := clients.NewAgent(c.ctx, addr, c.cfg.Conn)
 			if err != nil {
 				return fmt.Errorf("create agent for %s failed: %w when clean local data",
 					utils.StringifyAddr(addr), err)
@@ -105,8 +182,17 @@ func (c *Cleanup) cleanExternal() error {
 	return nil
 }
 
+// Report fills the report with the backup name and hosts of cleanup
+func (c *Cleanup) Report(rep *report.Report) {
+	rep.BackupName = c.cfg.BackupName
+	rep.Hosts = c.hosts.Hosts()
+}
+
 func (c *Cleanup) Clean() error {
 	logger := log.WithField("backup name", c.cfg.BackupName)
+	if err := c.checkReferred(); err != nil {
+		return err
+	}
 
 	logger.Info("Start to cleanup data in nebula cluster.")
 	err := c.cleanNebula()
@@ -115,7 +201,7 @@ func (c *Cleanup) Clean() error {
 	}
 
 	logger.Info("Start cleanup data in external storage.")
-	err = c.cleanExternal()
+	err = c.cleanExternal(c.cfg.BackupName)
 	if err != nil {
 		return fmt.Errorf("clean external storage data failed: %w", err)
 	}
@@ -0,0 +1,155 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vesoft-inc/nebula-br/pkg/config"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+)
+
+// Prune deletes the backups in external storage which are not kept by the retention policy
+type Prune struct {
+	ctx context.Context
+	cfg *config.PruneConfig
+	c   *Cleanup
+}
+
+type pruneItem struct {
+	backup  *utils.RetentionBackup
+	reasons []string
+}
+
+func NewPrune(ctx context.Context, cfg *config.PruneConfig) (*Prune, error) {
+	cleanupCfg := &config.CleanupConfig{
+		MetaAddr: cfg.MetaAddr,
+		Backend:  cfg.Backend,
+		Conn:     cfg.Conn,
+	}
+	// the backups in local storage lay in the cluster hosts, which are removed by agents
+	newCleanup := newExternalCleanup
+	if cfg.Backend.GetLocal() != nil {
+		newCleanup = NewCleanup
+	}
+	c, err := newCleanup(ctx, cleanupCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Prune{
+		ctx: ctx,
+		cfg: cfg,
+		c:   c,
+	}, nil
+}
+
+// listBackups lists and parses the backups under the storage root like show,
+// the broken ones are returned separately since their create time is unknown.
+func (p *Prune) listBackups() ([]*utils.RetentionBackup, []string, error) {
+	rootUri := p.cfg.Backend.Uri()
+	dirNames, err := p.c.sto.ListDir(p.ctx, rootUri)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list dir %s failed: %w", rootUri, err)
+	}
+
+	var backups []*utils.RetentionBackup
+	var broken []string
+	for _, name := range dirNames {
+		name = strings.Trim(name, "/") // the s3 list result may have slashes
+		if !utils.IsBackupName(name) {
+			log.Infof("%s is not backup name.", name)
+			continue
+		}
+
+		m, err := utils.DownloadMetaFile(p.ctx, p.c.sto, rootUri, name, p.cfg.EncryptionKey)
+		if err != nil {
+			log.WithError(err).WithField("backup", name).Error("Get backup meta file failed.")
+			broken = append(broken, name)
+			continue
+		}
+		backups = append(backups, &utils.RetentionBackup{
+			Name:       name,
+			CreateTime: time.Unix(0, m.CreateTime*int64(time.Millisecond)),
+			Base:       string(m.GetBaseBackupName()),
+		})
+	}
+	return backups, broken, nil
+}
+
+func (p *Prune) printPlan(w io.Writer, items []*pruneItem, broken []string) {
+	tw := tablewriter.NewWriter(w)
+	tw.SetHeader([]string{"name", "create_time", "action", "reasons"})
+	for _, item := range items {
+		action := "delete"
+		if len(item.reasons) != 0 {
+			action = "keep"
+		}
+		tw.Append([]string{
+			item.backup.Name,
+			item.backup.CreateTime.Format("2006-01-02 15:04:05"),
+			action,
+			strings.Join(item.reasons, ","),
+		})
+	}
+	for _, name := range broken {
+		tw.Append([]string{name, "N/A", "keep", "broken, skipped"})
+	}
+	tw.Render()
+}
+
+func (p *Prune) Prune() error {
+	if err := utils.EnsureDir(utils.LocalTmpDir); err != nil {
+		return err
+	}
+	defer func() {
+		if err := utils.RemoveDir(utils.LocalTmpDir); err != nil {
+			log.WithError(err).Errorf("Remove tmp dir %s failed.", utils.LocalTmpDir)
+		}
+	}()
+
+	backups, broken, err := p.listBackups()
+	if err != nil {
+		return err
+	}
+	reasons := p.cfg.Policy.Apply(backups, time.Now())
+
+	// delete from the latest, so incremental backups are deleted before their bases
+	items := make([]*pruneItem, 0, len(backups))
+	for _, b := range backups {
+		items = append(items, &pruneItem{backup: b, reasons: reasons[b.Name]})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].backup.CreateTime.After(items[j].backup.CreateTime)
+	})
+	p.printPlan(os.Stdout, items, broken)
+
+	if p.cfg.DryRun {
+		return nil
+	}
+
+	deleted := 0
+	for _, item := range items {
+		if len(item.reasons) != 0 {
+			continue
+		}
+
+		logger := log.WithField("backup", item.backup.Name)
+		logger.Info("Start to prune backup in external storage.")
+		if err := p.c.cleanExternal(item.backup.Name); err != nil {
+			return fmt.Errorf("prune backup %s failed, %d backups have been deleted: %w",
+				item.backup.Name, deleted, err)
+		}
+		deleted++
+		logger.Info("Prune backup successfully.")
+	}
+	fmt.Printf("Prune %d backups successfully.\n", deleted)
+	return nil
+}
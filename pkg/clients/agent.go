@@ -3,46 +3,182 @@ package clients
 import (
 	"context"
 	"fmt"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	agent "github.com/vesoft-inc/nebula-agent/pkg/client"
+	pb "github.com/vesoft-inc/nebula-agent/pkg/proto"
+	"github.com/vesoft-inc/nebula-br/pkg/config"
 	"github.com/vesoft-inc/nebula-br/pkg/utils"
 	"github.com/vesoft-inc/nebula-go/v3/nebula"
 )
 
+// NebulaAgent retries the agent calls by the retry policy
 type NebulaAgent struct {
 	agent.Client
+	ctx   context.Context
+	retry *utils.RetryPolicy
 }
 
-func NewAgent(ctx context.Context, agentAddr *nebula.HostAddr) (*NebulaAgent, error) {
-	cfg := &agent.Config{
-		Addr: agentAddr,
+// NewAgent connects the agent by TLS if it is enabled, the call timeouts only take effect with TLS,
+// since they could not be configured in the client of nebula-agent.
+func NewAgent(ctx context.Context, agentAddr *nebula.HostAddr, conn *config.ConnConfig) (*NebulaAgent, error) {
+	conn = connOrDefault(conn)
+	var (
+		c   agent.Client
+		err error
+	)
+	if conn.TLS != nil {
+		c, err = newTLSAgent(ctx, agentAddr, conn)
+	} else {
+		c, err = agent.New(ctx, &agent.Config{Addr: agentAddr})
 	}
-	c, err := agent.New(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
 
 	a := &NebulaAgent{
 		Client: c,
+		ctx:    ctx,
+		retry:  conn.Retry,
 	}
 
 	return a, nil
 }
 
+func (a *NebulaAgent) do(name string, f func() error) error {
+	return a.retry.Do(a.ctx, fmt.Sprintf("%s by agent %s", name, utils.StringifyAddr(a.GetAddr())), f)
+}
+
+// transfer does not retry the upload or download which timed out, since the agent may be still
+// transferring the files, and a retry would transfer the whole dir again alongside it.
+func (a *NebulaAgent) transfer(name string, f func() error) error {
+	return a.do(name, func() error {
+		err := f()
+		if status.Code(err) == codes.DeadlineExceeded {
+			return utils.Fatal(err)
+		}
+		return err
+	})
+}
+
+func (a *NebulaAgent) UploadFile(req *pb.UploadFileRequest) (resp *pb.UploadFileResponse, err error) {
+	err = a.transfer("Upload file", func() error {
+		resp, err = a.Client.UploadFile(req)
+		return err
+	})
+	return resp, err
+}
+
+func (a *NebulaAgent) DownloadFile(req *pb.DownloadFileRequest) (resp *pb.DownloadFileResponse, err error) {
+	err = a.transfer("Download file", func() error {
+		resp, err = a.Client.DownloadFile(req)
+		return err
+	})
+	return resp, err
+}
+
+// MoveDir is not idempotent, so before a retry it checks whether the failed attempt has moved the dir,
+// the move is taken as done if the destination exists and the source does not.
+func (a *NebulaAgent) MoveDir(req *pb.MoveDirRequest) (resp *pb.MoveDirResponse, err error) {
+	attempt := 0
+	err = a.do("Move dir", func() error {
+		attempt++
+		if attempt > 1 {
+			moved, err := a.moved(req)
+			if err != nil {
+				return err
+			}
+			if moved {
+				resp = &pb.MoveDirResponse{}
+				return nil
+			}
+		}
+		resp, err = a.Client.MoveDir(req)
+		return err
+	})
+	return resp, err
+}
+
+func (a *NebulaAgent) moved(req *pb.MoveDirRequest) (bool, error) {
+	dst, err := a.Client.ExistDir(&pb.ExistDirRequest{Path: req.DstPath})
+	if err != nil {
+		return false, err
+	}
+	if !dst.Exist {
+		return false, nil
+	}
+	src, err := a.Client.ExistDir(&pb.ExistDirRequest{Path: req.SrcPath})
+	if err != nil {
+		return false, err
+	}
+	return !src.Exist, nil
+}
+
+func (a *NebulaAgent) RemoveDir(req *pb.RemoveDirRequest) (resp *pb.RemoveDirResponse, err error) {
+	err = a.do("Remove dir", func() error {
+		resp, err = a.Client.RemoveDir(req)
+		return err
+	})
+	return resp, err
+}
+
+func (a *NebulaAgent) ExistDir(req *pb.ExistDirRequest) (resp *pb.ExistDirResponse, err error) {
+	err = a.do("Check dir", func() error {
+		resp, err = a.Client.ExistDir(req)
+		return err
+	})
+	return resp, err
+}
+
+func (a *NebulaAgent) StartService(req *pb.StartServiceRequest) (resp *pb.StartServiceResponse, err error) {
+	err = a.do("Start service", func() error {
+		resp, err = a.Client.StartService(req)
+		return err
+	})
+	return resp, err
+}
+
+func (a *NebulaAgent) StopService(req *pb.StopServiceRequest) (resp *pb.StopServiceResponse, err error) {
+	err = a.do("Stop service", func() error {
+		resp, err = a.Client.StopService(req)
+		return err
+	})
+	return resp, err
+}
+
+func (a *NebulaAgent) ServiceStatus(req *pb.ServiceStatusRequest) (resp *pb.ServiceStatusResponse, err error) {
+	err = a.do("Get service status", func() error {
+		resp, err = a.Client.ServiceStatus(req)
+		return err
+	})
+	return resp, err
+}
+
 type AgentManager struct {
 	ctx    context.Context
-	agents map[string]*NebulaAgent // group by ip or host
 	hosts  *utils.NebulaHosts
+	conn   *config.ConnConfig
+	mu     sync.Mutex
+	agents map[string]*NebulaAgent // group by ip or host
 }
 
-func NewAgentManager(ctx context.Context, hosts *utils.NebulaHosts) *AgentManager {
+func NewAgentManager(ctx context.Context, hosts *utils.NebulaHosts, conn *config.ConnConfig) *AgentManager {
 	return &AgentManager{
 		ctx:    ctx,
 		agents: make(map[string]*NebulaAgent),
 		hosts:  hosts,
+		conn:   conn,
 	}
 }
 
+// RetryPolicy returns the retry policy of the agents
+func (a *AgentManager) RetryPolicy() *utils.RetryPolicy {
+	return connOrDefault(a.conn).Retry
+}
+
 func (a *AgentManager) GetAgentFor(serviceAddr *nebula.HostAddr) (*NebulaAgent, error) {
 	agentAddr, err := a.hosts.GetAgentFor(serviceAddr)
 	if err != nil {
@@ -54,6 +190,9 @@ func (a *AgentManager) GetAgentFor(serviceAddr *nebula.HostAddr) (*NebulaAgent,
 }
 
 func (a *AgentManager) GetAgent(agentAddr *nebula.HostAddr) (*NebulaAgent, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
 	if agent, ok := a.agents[agentAddr.Host]; ok {
 		if agent.GetAddr().Host != agentAddr.Host || agent.GetAddr().Port != agentAddr.Port {
 			return nil, fmt.Errorf("there are two agents, %s and %s, in the same host: %s",
@@ -63,7 +202,7 @@ func (a *AgentManager) GetAgent(agentAddr *nebula.HostAddr) (*NebulaAgent, error
 		return agent, nil
 	}
 
-	agent, err := NewAgent(a.ctx, agentAddr)
+	agent, err := NewAgent(a.ctx, agentAddr, a.conn)
 	if err != nil {
 		return nil, fmt.Errorf("create agent %s failed: %w", utils.StringifyAddr(agentAddr), err)
 	}
@@ -0,0 +1,82 @@
+package clients
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	agent "github.com/vesoft-inc/nebula-agent/pkg/client"
+	pb "github.com/vesoft-inc/nebula-agent/pkg/proto"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+	"github.com/vesoft-inc/nebula-go/v3/nebula"
+)
+
+// fakeAgent moves the dir but loses the response of the first MoveDir,
+// and times out every download
+type fakeAgent struct {
+	agent.Client
+	dirs      map[string]bool
+	moves     int
+	downloads int
+}
+
+func (f *fakeAgent) GetAddr() *nebula.HostAddr {
+	return &nebula.HostAddr{Host: "192.168.0.1", Port: 8888}
+}
+
+func (f *fakeAgent) ExistDir(req *pb.ExistDirRequest) (*pb.ExistDirResponse, error) {
+	return &pb.ExistDirResponse{Exist: f.dirs[req.Path]}, nil
+}
+
+func (f *fakeAgent) MoveDir(req *pb.MoveDirRequest) (*pb.MoveDirResponse, error) {
+	f.moves++
+	if !f.dirs[req.SrcPath] {
+		return nil, status.Error(codes.NotFound, "no such dir")
+	}
+	delete(f.dirs, req.SrcPath)
+	f.dirs[req.DstPath] = true
+	if f.moves == 1 {
+		return nil, status.Error(codes.Unavailable, "connection reset")
+	}
+	return &pb.MoveDirResponse{}, nil
+}
+
+func (f *fakeAgent) DownloadFile(req *pb.DownloadFileRequest) (*pb.DownloadFileResponse, error) {
+	f.downloads++
+	return nil, status.Error(codes.DeadlineExceeded, "deadline exceeded")
+}
+
+func newFakeAgent() (*NebulaAgent, *fakeAgent) {
+	f := &fakeAgent{dirs: map[string]bool{"/data/nebula": true}}
+	a := &NebulaAgent{
+		Client: f,
+		ctx:    context.Background(),
+		retry:  &utils.RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+	}
+	return a, f
+}
+
+func TestAgentMoveDirRetry(t *testing.T) {
+	assert := assert.New(t)
+
+	a, f := newFakeAgent()
+	_, err := a.MoveDir(&pb.MoveDirRequest{SrcPath: "/data/nebula", DstPath: "/data/nebula_old"})
+	assert.Nil(err)
+	assert.Equal(1, f.moves)
+	assert.True(f.dirs["/data/nebula_old"])
+	assert.False(f.dirs["/data/nebula"])
+}
+
+func TestAgentTransferTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	a, f := newFakeAgent()
+	_, err := a.DownloadFile(&pb.DownloadFileRequest{})
+	assert.NotNil(err)
+	assert.False(utils.Retryable(err))
+	assert.Equal(1, f.downloads)
+}
@@ -1,35 +1,71 @@
 package clients
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/vesoft-inc/nebula-br/pkg/config"
 	"github.com/vesoft-inc/nebula-br/pkg/utils"
 	"github.com/vesoft-inc/nebula-go/v3/nebula"
 	"github.com/vesoft-inc/nebula-go/v3/nebula/meta"
 )
 
-type NebulaMeta struct {
-	client     *meta.MetaServiceClient
-	leaderAddr *nebula.HostAddr
+// metaService is the part of meta service client called by NebulaMeta, which could be faked in tests
+type metaService interface {
+	Close() error
+	ListCluster(req *meta.ListClusterInfoReq) (*meta.ListClusterInfoResp, error)
+	CreateBackup(req *meta.CreateBackupReq) (*meta.CreateBackupResp, error)
+	DropSnapshot(req *meta.DropSnapshotReq) (*meta.ExecResp, error)
+	ListHosts(req *meta.ListHostsReq) (*meta.ListHostsResp, error)
+	GetSpace(req *meta.GetSpaceReq) (*meta.GetSpaceResp, error)
+	ListSpaces(req *meta.ListSpacesReq) (*meta.ListSpacesResp, error)
+	GetPartsAlloc(req *meta.GetPartsAllocReq) (*meta.GetPartsAllocResp, error)
+	DropSpace(req *meta.DropSpaceReq) (*meta.ExecResp, error)
 }
 
-func NewMeta(addrStr string) (*NebulaMeta, error) {
-	addr, err := utils.ParseAddr(addrStr)
+func dialMeta(addr *nebula.HostAddr, conn *config.ConnConfig) (metaService, error) {
+	c, err := connect(addr, conn)
 	if err != nil {
 		return nil, err
 	}
+	return c, nil
+}
+
+// NebulaMeta calls the meta leader, it fails over to other metads in turn
+// when the current one could not be connected.
+type NebulaMeta struct {
+	client     metaService
+	leaderAddr *nebula.HostAddr
+	addrs      []*nebula.HostAddr // all the known metads, from flag and ListCluster
+	conn       *config.ConnConfig
+	dial       func(addr *nebula.HostAddr, conn *config.ConnConfig) (metaService, error)
+}
 
-	m := &NebulaMeta{
-		leaderAddr: addr,
+// NewMeta connects the first available metad in addrStr, which is a comma-separated address list
+func NewMeta(addrStr string, conn *config.ConnConfig) (*NebulaMeta, error) {
+	m := &NebulaMeta{conn: connOrDefault(conn), dial: dialMeta}
+	for _, s := range strings.Split(addrStr, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		addr, err := utils.ParseAddr(s)
+		if err != nil {
+			return nil, err
+		}
+		m.addPeer(addr)
+	}
+	if len(m.addrs) == 0 {
+		return nil, fmt.Errorf("no meta address in %q", addrStr)
 	}
 
-	if m.client, err = connect(addr); err != nil {
+	if err := m.failover(); err != nil {
 		return nil, err
 	}
-
 	return m, nil
 }
 
@@ -37,59 +73,161 @@ func (m *NebulaMeta) LeaderAddr() *nebula.HostAddr {
 	return m.leaderAddr
 }
 
+func (m *NebulaMeta) Close() error {
+	return m.client.Close()
+}
+
+func (m *NebulaMeta) addPeer(addr *nebula.HostAddr) {
+	for _, a := range m.addrs {
+		if a.GetHost() == addr.GetHost() && a.GetPort() == addr.GetPort() {
+			return
+		}
+	}
+	m.addrs = append(m.addrs, addr)
+}
+
+// failover connects the known metads in turn, starting from the one after the current
+func (m *NebulaMeta) failover() error {
+	start := 0
+	for i, a := range m.addrs {
+		if m.leaderAddr != nil && a.GetHost() == m.leaderAddr.GetHost() && a.GetPort() == m.leaderAddr.GetPort() {
+			start = i + 1
+			break
+		}
+	}
+
+	var errs []string
+	for i := 0; i < len(m.addrs); i++ {
+		addr := m.addrs[(start+i)%len(m.addrs)]
+		c, err := m.dial(addr, m.conn)
+		if err != nil {
+			log.WithError(err).WithField("addr", utils.StringifyAddr(addr)).Warn("Connect to metad failed, try the next one.")
+			errs = append(errs, fmt.Sprintf("%s: %v", utils.StringifyAddr(addr), err))
+			continue
+		}
+
+		if m.client != nil {
+			m.client.Close()
+		}
+		m.client = c
+		m.leaderAddr = addr
+		return nil
+	}
+	return fmt.Errorf("connect to all the metads failed: %s", strings.Join(errs, "; "))
+}
+
 func (m *NebulaMeta) reconnect(addr *nebula.HostAddr) error {
 	if addr == meta.ExecResp_Leader_DEFAULT {
 		return fmt.Errorf("leader not found when call ListCluster")
 	}
 	m.client.Close()
 
-	c, err := connect(addr)
+	c, err := m.dial(addr, m.conn)
 	if err != nil {
 		return fmt.Errorf("connect to new meta client leader %s failed: %w",
 			utils.StringifyAddr(addr), err)
 	}
 
+	m.addPeer(addr)
 	m.leaderAddr = addr
 	m.client = c
 	return nil
 }
 
-func (m *NebulaMeta) ListCluster() (*meta.ListClusterInfoResp, error) {
-	req := &meta.ListClusterInfoReq{}
+// metaCall calls the meta service, and returns the code and leader in response,
+// the response should not be read when the call returns an error since it is nil.
+type metaCall func(c metaService) (nebula.ErrorCode, *nebula.HostAddr, error)
+
+// call retries the call on the new leader when leader changed, and fails over to other
+// metads with backoff when the call failed, until every known metad has been tried
+// and the attempts in retry policy run out.
+func (m *NebulaMeta) call(name string, f metaCall) error {
+	return m.do(name, true, f)
+}
+
+// callOnce does not retry or fail over when the call failed, for the calls which are not
+// idempotent, since the request may have been executed when the response is lost.
+// It is still retried on the new leader, since the old one has rejected it.
+func (m *NebulaMeta) callOnce(name string, f metaCall) error {
+	return m.do(name, false, f)
+}
+
+func (m *NebulaMeta) do(name string, retry bool, f metaCall) error {
+	policy := m.conn.Retry
+	attempts := policy.MaxAttempts
+	if attempts < len(m.addrs) {
+		attempts = len(m.addrs)
+	}
+	if !retry {
+		attempts = 1
+	}
 
+	failed := 0
 	for {
-		resp, err := m.client.ListCluster(req)
+		code, leader, err := f(m.client)
 		if err != nil {
-			return nil, fmt.Errorf("list cluster %s failed: %w", utils.StringifyAddr(m.leaderAddr), err)
+			failed++
+			if failed >= attempts || !utils.Retryable(err) {
+				return fmt.Errorf("call %s to %s failed after %d attempts: %w",
+					name, utils.StringifyAddr(m.leaderAddr), failed, err)
+			}
+			backoff := policy.Backoff(failed + 1)
+			log.WithError(err).WithField("addr", utils.StringifyAddr(m.leaderAddr)).
+				WithField("backoff", backoff).Warnf("Call %s failed, fail over to other metads.", name)
+			time.Sleep(backoff)
+			if ferr := m.failover(); ferr != nil {
+				return fmt.Errorf("call %s to %s failed: %w, and %v", name, utils.StringifyAddr(m.leaderAddr), err, ferr)
+			}
+			continue
 		}
 
-		// retry only when leader change
-		if resp.GetCode() == nebula.ErrorCode_E_LEADER_CHANGED {
-			err := m.reconnect(resp.GetLeader())
-			if err != nil {
-				return nil, err
+		// retry when leader change
+		if code == nebula.ErrorCode_E_LEADER_CHANGED {
+			if err := m.reconnect(leader); err != nil {
+				return err
 			}
 			continue
 		}
+		return nil
+	}
+}
+
+func (m *NebulaMeta) ListCluster() (*meta.ListClusterInfoResp, error) {
+	req := &meta.ListClusterInfoReq{}
+
+	var resp *meta.ListClusterInfoResp
+	err := m.call("ListCluster", func(c metaService) (nebula.ErrorCode, *nebula.HostAddr, error) {
+		var err error
+		resp, err = c.ListCluster(req)
+		if err != nil {
+			return 0, nil, err
+		}
+		return resp.GetCode(), resp.GetLeader(), err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list cluster failed: %w", err)
+	}
 
-		// fill the meta dir info
-		for _, services := range resp.GetHostServices() {
-			for _, s := range services {
-				if s.Role == meta.HostRole_META {
-					dir, err := m.getMetaDirInfo(s.GetAddr())
-					if err != nil {
-						return nil, fmt.Errorf("get meta %s from address failed: %w",
-							utils.StringifyAddr(s.GetAddr()), err)
-					}
-					s.Dir = dir
+	// fill the meta dir info, and remember all the metads to fail over
+	for _, services := range resp.GetHostServices() {
+		for _, s := range services {
+			if s.Role == meta.HostRole_META {
+				m.addPeer(s.GetAddr())
+				dir, err := m.getMetaDirInfo(s.GetAddr())
+				if err != nil {
+					return nil, fmt.Errorf("get meta %s from address failed: %w",
+						utils.StringifyAddr(s.GetAddr()), err)
 				}
+				s.Dir = dir
 			}
 		}
-
-		return resp, nil
 	}
+
+	return resp, nil
 }
 
+// CreateBackup is not retried or failed over, since a retry may create another backup
+// if the request is lost after executed, which would be left in cluster.
 func (m *NebulaMeta) CreateBackup(spaces []string) (*meta.CreateBackupResp, error) {
 	req := meta.NewCreateBackupReq()
 
@@ -98,23 +236,19 @@ func (m *NebulaMeta) CreateBackup(spaces []string) (*meta.CreateBackupResp, erro
 		req.Spaces = append(req.Spaces, []byte(space))
 	}
 
-	for {
-		resp, err := m.client.CreateBackup(req)
+	var resp *meta.CreateBackupResp
+	err := m.callOnce("CreateBackup", func(c metaService) (nebula.ErrorCode, *nebula.HostAddr, error) {
+		var err error
+		resp, err = c.CreateBackup(req)
 		if err != nil {
-			return nil, err
-		}
-
-		if resp.GetCode() == nebula.ErrorCode_E_LEADER_CHANGED {
-			err := m.reconnect(resp.GetLeader())
-			if err != nil {
-				return nil, err
-			}
-			continue
+			return 0, nil, err
 		}
-
-		return resp, nil
+		return resp.GetCode(), resp.GetLeader(), err
+	})
+	if err != nil {
+		return nil, err
 	}
-
+	return resp, nil
 }
 
 func (m *NebulaMeta) DropBackup(name []byte) error {
@@ -122,48 +256,112 @@ func (m *NebulaMeta) DropBackup(name []byte) error {
 	// for nebulaGraph 3.3.0 compatibility
 	req.Names = [][]byte{name}
 
-	for {
-		resp, err := m.client.DropSnapshot(req)
+	var resp *meta.ExecResp
+	err := m.call("DropSnapshot", func(c metaService) (nebula.ErrorCode, *nebula.HostAddr, error) {
+		var err error
+		resp, err = c.DropSnapshot(req)
 		if err != nil {
-			return fmt.Errorf("call drop snapshot failed: %w", err)
+			return 0, nil, err
 		}
+		return resp.GetCode(), resp.GetLeader(), err
+	})
+	if err != nil {
+		return fmt.Errorf("call drop snapshot failed: %w", err)
+	}
 
-		if resp.GetCode() == nebula.ErrorCode_E_LEADER_CHANGED {
-			err := m.reconnect(resp.GetLeader())
-			if err != nil {
-				return err
-			}
-			continue
-		}
+	if resp.GetCode() == nebula.ErrorCode_SUCCEEDED {
+		return nil
+	}
+	return fmt.Errorf("call drop snapshot failed: %s", resp.GetCode().String())
+}
 
-		if resp.GetCode() == nebula.ErrorCode_SUCCEEDED {
-			return nil
+// ListHosts lists the services of the role with their status and version
+func (m *NebulaMeta) ListHosts(typ meta.ListHostType) ([]*meta.HostItem, error) {
+	req := meta.NewListHostsReq()
+	req.Type = typ
+
+	var resp *meta.ListHostsResp
+	err := m.call("ListHosts", func(c metaService) (nebula.ErrorCode, *nebula.HostAddr, error) {
+		var err error
+		resp, err = c.ListHosts(req)
+		if err != nil {
+			return 0, nil, err
 		}
-		return fmt.Errorf("call drop snapshot failed: %s", resp.GetCode().String())
+		return resp.GetCode(), resp.GetLeader(), err
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	if resp.GetCode() != nebula.ErrorCode_SUCCEEDED {
+		return nil, fmt.Errorf("call ListHosts failed: %s", resp.GetCode().String())
+	}
+	return resp.GetHosts(), nil
 }
 
 func (m *NebulaMeta) GetSpace(space []byte) (*meta.GetSpaceResp, error) {
 	req := meta.NewGetSpaceReq()
 	req.SpaceName = space
 
-	for {
-		resp, err := m.client.GetSpace(req)
+	var resp *meta.GetSpaceResp
+	err := m.call("GetSpace", func(c metaService) (nebula.ErrorCode, *nebula.HostAddr, error) {
+		var err error
+		resp, err = c.GetSpace(req)
 		if err != nil {
-			return nil, err
+			return 0, nil, err
 		}
+		return resp.GetCode(), resp.GetLeader(), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
 
-		if resp.GetCode() == nebula.ErrorCode_E_LEADER_CHANGED {
-			err := m.reconnect(resp.GetLeader())
-			if err != nil {
-				return nil, err
-			}
-			continue
+func (m *NebulaMeta) ListSpaces() ([]*meta.IdName, error) {
+	req := meta.NewListSpacesReq()
+
+	var resp *meta.ListSpacesResp
+	err := m.call("ListSpaces", func(c metaService) (nebula.ErrorCode, *nebula.HostAddr, error) {
+		var err error
+		resp, err = c.ListSpaces(req)
+		if err != nil {
+			return 0, nil, err
+		}
+		return resp.GetCode(), resp.GetLeader(), err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.GetCode() != nebula.ErrorCode_SUCCEEDED {
+		return nil, fmt.Errorf("call ListSpaces failed: %s", resp.GetCode().String())
+	}
+	return resp.GetSpaces(), nil
+}
+
+// GetPartsAlloc returns the storaged hosts of every partition in the space
+func (m *NebulaMeta) GetPartsAlloc(spaceID nebula.GraphSpaceID) (map[nebula.PartitionID][]*nebula.HostAddr, error) {
+	req := meta.NewGetPartsAllocReq()
+	req.SpaceID = spaceID
+
+	var resp *meta.GetPartsAllocResp
+	err := m.call("GetPartsAlloc", func(c metaService) (nebula.ErrorCode, *nebula.HostAddr, error) {
+		var err error
+		resp, err = c.GetPartsAlloc(req)
+		if err != nil {
+			return 0, nil, err
 		}
+		return resp.GetCode(), resp.GetLeader(), err
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		return resp, nil
+	if resp.GetCode() != nebula.ErrorCode_SUCCEEDED {
+		return nil, fmt.Errorf("call GetPartsAlloc failed: %s", resp.GetCode().String())
 	}
+	return resp.GetParts(), nil
 }
 
 func (m *NebulaMeta) DropSpace(space []byte, ifExists bool) error {
@@ -171,29 +369,33 @@ func (m *NebulaMeta) DropSpace(space []byte, ifExists bool) error {
 	req.SpaceName = space
 	req.IfExists = ifExists
 
-	for {
-		resp, err := m.client.DropSpace(req)
+	var resp *meta.ExecResp
+	err := m.call("DropSpace", func(c metaService) (nebula.ErrorCode, *nebula.HostAddr, error) {
+		var err error
+		resp, err = c.DropSpace(req)
 		if err != nil {
-			return err
-		}
-
-		if resp.GetCode() == nebula.ErrorCode_E_LEADER_CHANGED {
-			err := m.reconnect(resp.GetLeader())
-			if err != nil {
-				return err
-			}
-			continue
+			return 0, nil, err
 		}
+		return resp.GetCode(), resp.GetLeader(), err
+	})
+	if err != nil {
+		return err
+	}
 
-		if resp.GetCode() == nebula.ErrorCode_SUCCEEDED {
-			return nil
-		}
-		return fmt.Errorf("call DropSpace failed: %s", resp.GetCode().String())
+	if resp.GetCode() == nebula.ErrorCode_SUCCEEDED {
+		return nil
 	}
+	return fmt.Errorf("call DropSpace failed: %s", resp.GetCode().String())
 }
 
+// metad may take a long time to start up, so restore meta is tried at least 10 times
+const restoreMetaAttempts = 10
+
 // single metad node
-func (m *NebulaMeta) RestoreMeta(metaAddr *nebula.HostAddr, hostMap []*meta.HostPair, files []string) error {
+// RestoreMeta connects the metad directly instead of the leader, so it could be
+// called without a NebulaMeta, e.g. when the cluster is restarted by resuming restore.
+func RestoreMeta(metaAddr *nebula.HostAddr, conn *config.ConnConfig, hostMap []*meta.HostPair, files []string) error {
+	conn = connOrDefault(conn)
 	byteFiles := make([][]byte, 0, len(files))
 	for _, f := range files {
 		byteFiles = append(byteFiles, []byte(f))
@@ -202,39 +404,36 @@ func (m *NebulaMeta) RestoreMeta(metaAddr *nebula.HostAddr, hostMap []*meta.Host
 	req.Hosts = hostMap
 	req.Files = byteFiles
 
-	// meta startup time may be very long, so add retry for up to 10 times
-	for try := 1; try <= 10; try++ {
-		client, err := connect(metaAddr)
+	policy := *conn.Retry
+	if policy.MaxAttempts < restoreMetaAttempts {
+		policy.MaxAttempts = restoreMetaAttempts
+	}
+	name := fmt.Sprintf("restore meta in %s", utils.StringifyAddr(metaAddr))
+	return policy.Do(context.Background(), name, func() error {
+		client, err := connect(metaAddr, conn)
 		if err != nil {
-			numsec := 1 << try
-			if numsec > 32 {
-				numsec = 32
-			}
-			log.WithError(err).WithField("addr", utils.StringifyAddr(metaAddr)).
-				Errorf("Connect to metad failed, will try after %d seconds, try times %d.", numsec, try)
-			time.Sleep(time.Second * time.Duration(numsec))
-			continue
+			return err
 		}
+		defer client.Close()
 
+		// the restore request is not retried, only the connection is
 		resp, err := client.RestoreMeta(req)
 		if err != nil {
 			log.WithError(err).WithField("req", req).Error("Restore meta failed.")
-			return err
+			return utils.Fatal(err)
 		}
 
 		if resp.GetCode() == nebula.ErrorCode_SUCCEEDED {
 			return nil
 		}
-		return fmt.Errorf("call %s:RestoreMeta failed: %s",
-			utils.StringifyAddr(metaAddr), resp.GetCode().String())
-	}
-
-	return fmt.Errorf("try to connect %s 3 times, but failed", utils.StringifyAddr(metaAddr))
+		return utils.Fatal(fmt.Errorf("call %s:RestoreMeta failed: %s",
+			utils.StringifyAddr(metaAddr), resp.GetCode().String()))
+	})
 }
 
 func (m *NebulaMeta) getMetaDirInfo(addr *nebula.HostAddr) (*nebula.DirInfo, error) {
 	log.WithField("addr", utils.StringifyAddr(addr)).Debug("Try to get dir info from meta service.")
-	c, err := connect(addr)
+	c, err := connect(addr, m.conn)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,168 @@
+package clients
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vesoft-inc/nebula-br/pkg/config"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+	"github.com/vesoft-inc/nebula-go/v3/nebula"
+	"github.com/vesoft-inc/nebula-go/v3/nebula/meta"
+)
+
+// fakeMeta fails every call with a transport error and a nil response if down
+type fakeMeta struct {
+	addr  string
+	down  bool
+	calls map[string]int
+}
+
+func (f *fakeMeta) call(name string) error {
+	f.calls[name]++
+	if f.down {
+		return fmt.Errorf("connection to %s reset", f.addr)
+	}
+	return nil
+}
+
+func (f *fakeMeta) Close() error { return nil }
+
+func (f *fakeMeta) ListCluster(req *meta.ListClusterInfoReq) (*meta.ListClusterInfoResp, error) {
+	if err := f.call("ListCluster"); err != nil {
+		return nil, err
+	}
+	return &meta.ListClusterInfoResp{Code: nebula.ErrorCode_SUCCEEDED}, nil
+}
+
+func (f *fakeMeta) CreateBackup(req *meta.CreateBackupReq) (*meta.CreateBackupResp, error) {
+	if err := f.call("CreateBackup"); err != nil {
+		return nil, err
+	}
+	return &meta.CreateBackupResp{Code: nebula.ErrorCode_SUCCEEDED}, nil
+}
+
+func (f *fakeMeta) DropSnapshot(req *meta.DropSnapshotReq) (*meta.ExecResp, error) {
+	if err := f.call("DropSnapshot"); err != nil {
+		return nil, err
+	}
+	return &meta.ExecResp{Code: nebula.ErrorCode_SUCCEEDED}, nil
+}
+
+func (f *fakeMeta) ListHosts(req *meta.ListHostsReq) (*meta.ListHostsResp, error) {
+	if err := f.call("ListHosts"); err != nil {
+		return nil, err
+	}
+	return &meta.ListHostsResp{Code: nebula.ErrorCode_SUCCEEDED}, nil
+}
+
+func (f *fakeMeta) GetSpace(req *meta.GetSpaceReq) (*meta.GetSpaceResp, error) {
+	if err := f.call("GetSpace"); err != nil {
+		return nil, err
+	}
+	return &meta.GetSpaceResp{Code: nebula.ErrorCode_SUCCEEDED}, nil
+}
+
+func (f *fakeMeta) ListSpaces(req *meta.ListSpacesReq) (*meta.ListSpacesResp, error) {
+	if err := f.call("ListSpaces"); err != nil {
+		return nil, err
+	}
+	return &meta.ListSpacesResp{Code: nebula.ErrorCode_SUCCEEDED}, nil
+}
+
+func (f *fakeMeta) GetPartsAlloc(req *meta.GetPartsAllocReq) (*meta.GetPartsAllocResp, error) {
+	if err := f.call("GetPartsAlloc"); err != nil {
+		return nil, err
+	}
+	return &meta.GetPartsAllocResp{Code: nebula.ErrorCode_SUCCEEDED}, nil
+}
+
+func (f *fakeMeta) DropSpace(req *meta.DropSpaceReq) (*meta.ExecResp, error) {
+	if err := f.call("DropSpace"); err != nil {
+		return nil, err
+	}
+	return &meta.ExecResp{Code: nebula.ErrorCode_SUCCEEDED}, nil
+}
+
+// newFakeMeta returns a NebulaMeta connected to the first metad, and the metads in addrs are down if set true
+func newFakeMeta(t *testing.T, attempts int, addrs map[string]bool) (*NebulaMeta, map[string]*fakeMeta, *int) {
+	fakes := make(map[string]*fakeMeta)
+	dials := 0
+	m := &NebulaMeta{
+		conn: &config.ConnConfig{Retry: &utils.RetryPolicy{
+			MaxAttempts: attempts,
+			BaseBackoff: time.Millisecond,
+			MaxBackoff:  time.Millisecond,
+		}},
+		dial: func(addr *nebula.HostAddr, conn *config.ConnConfig) (metaService, error) {
+			dials++
+			s := utils.StringifyAddr(addr)
+			f, ok := fakes[s]
+			if !ok {
+				f = &fakeMeta{addr: s, down: addrs[s], calls: make(map[string]int)}
+				fakes[s] = f
+			}
+			return f, nil
+		},
+	}
+	for _, s := range []string{"192.168.0.1:9559", "192.168.0.2:9559", "192.168.0.3:9559"} {
+		if _, ok := addrs[s]; !ok {
+			continue
+		}
+		addr, err := utils.ParseAddr(s)
+		assert.Nil(t, err)
+		m.addPeer(addr)
+	}
+	assert.Nil(t, m.failover())
+	dials = 0
+	return m, fakes, &dials
+}
+
+func TestMetaFailover(t *testing.T) {
+	assert := assert.New(t)
+
+	m, fakes, dials := newFakeMeta(t, 3, map[string]bool{
+		"192.168.0.1:9559": true,
+		"192.168.0.2:9559": false,
+	})
+	_, err := m.ListHosts(meta.ListHostType_STORAGE)
+	assert.Nil(err)
+	assert.Equal(1, *dials)
+	assert.Equal("192.168.0.2:9559", utils.StringifyAddr(m.LeaderAddr()))
+	assert.Equal(1, fakes["192.168.0.1:9559"].calls["ListHosts"])
+	assert.Equal(1, fakes["192.168.0.2:9559"].calls["ListHosts"])
+}
+
+func TestMetaGiveUp(t *testing.T) {
+	assert := assert.New(t)
+
+	m, fakes, _ := newFakeMeta(t, 3, map[string]bool{
+		"192.168.0.1:9559": true,
+		"192.168.0.2:9559": true,
+	})
+	_, err := m.ListSpaces()
+	assert.NotNil(err)
+	assert.Equal(2, fakes["192.168.0.1:9559"].calls["ListSpaces"])
+	assert.Equal(1, fakes["192.168.0.2:9559"].calls["ListSpaces"])
+
+	err = m.DropSpace([]byte("basic"), true)
+	assert.NotNil(err)
+	_, err = m.GetPartsAlloc(1)
+	assert.NotNil(err)
+}
+
+func TestMetaCreateBackupOnce(t *testing.T) {
+	assert := assert.New(t)
+
+	m, fakes, dials := newFakeMeta(t, 3, map[string]bool{
+		"192.168.0.1:9559": true,
+		"192.168.0.2:9559": false,
+	})
+	_, err := m.CreateBackup(nil)
+	assert.NotNil(err)
+	assert.Equal(0, *dials)
+	assert.Equal(1, fakes["192.168.0.1:9559"].calls["CreateBackup"])
+	assert.Equal("192.168.0.1:9559", utils.StringifyAddr(m.LeaderAddr()))
+}
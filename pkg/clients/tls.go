@@ -0,0 +1,106 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	agent "github.com/vesoft-inc/nebula-agent/pkg/client"
+	pb "github.com/vesoft-inc/nebula-agent/pkg/proto"
+	"github.com/vesoft-inc/nebula-br/pkg/config"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+	"github.com/vesoft-inc/nebula-go/v3/nebula"
+)
+
+// tlsAgent is the same as the client in nebula-agent, except that the grpc connection
+// is secured by TLS and every call is limited by timeout, which could not be configured there.
+type tlsAgent struct {
+	ctx             context.Context
+	addr            *nebula.HostAddr
+	timeout         time.Duration
+	transferTimeout time.Duration
+	agent           pb.AgentServiceClient
+	storage         pb.StorageServiceClient
+}
+
+var _ agent.Client = (*tlsAgent)(nil)
+
+func newTLSAgent(ctx context.Context, addr *nebula.HostAddr, conn *config.ConnConfig) (*tlsAgent, error) {
+	cc, err := grpc.DialContext(ctx, utils.StringifyAddr(addr),
+		grpc.WithTransportCredentials(credentials.NewTLS(conn.TLS)))
+	if err != nil {
+		return nil, fmt.Errorf("connect to agent %s by tls failed: %w", utils.StringifyAddr(addr), err)
+	}
+
+	return &tlsAgent{
+		ctx:             ctx,
+		addr:            addr,
+		timeout:         conn.Retry.Timeout,
+		transferTimeout: conn.Retry.TransferTimeout,
+		agent:           pb.NewAgentServiceClient(cc),
+		storage:         pb.NewStorageServiceClient(cc),
+	}, nil
+}
+
+// withTimeout returns the context of a call, timeout 0 means no timeout
+func (a *tlsAgent) withTimeout(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(a.ctx)
+	}
+	return context.WithTimeout(a.ctx, timeout)
+}
+
+func (a *tlsAgent) GetAddr() *nebula.HostAddr {
+	return a.addr
+}
+
+func (a *tlsAgent) UploadFile(req *pb.UploadFileRequest) (*pb.UploadFileResponse, error) {
+	ctx, cancel := a.withTimeout(a.transferTimeout)
+	defer cancel()
+	return a.storage.UploadFile(ctx, req)
+}
+
+func (a *tlsAgent) DownloadFile(req *pb.DownloadFileRequest) (*pb.DownloadFileResponse, error) {
+	ctx, cancel := a.withTimeout(a.transferTimeout)
+	defer cancel()
+	return a.storage.DownloadFile(ctx, req)
+}
+
+func (a *tlsAgent) MoveDir(req *pb.MoveDirRequest) (*pb.MoveDirResponse, error) {
+	ctx, cancel := a.withTimeout(a.timeout)
+	defer cancel()
+	return a.storage.MoveDir(ctx, req)
+}
+
+func (a *tlsAgent) RemoveDir(req *pb.RemoveDirRequest) (*pb.RemoveDirResponse, error) {
+	ctx, cancel := a.withTimeout(a.timeout)
+	defer cancel()
+	return a.storage.RemoveDir(ctx, req)
+}
+
+func (a *tlsAgent) ExistDir(req *pb.ExistDirRequest) (*pb.ExistDirResponse, error) {
+	ctx, cancel := a.withTimeout(a.timeout)
+	defer cancel()
+	return a.storage.ExistDir(ctx, req)
+}
+
+func (a *tlsAgent) StartService(req *pb.StartServiceRequest) (*pb.StartServiceResponse, error) {
+	ctx, cancel := a.withTimeout(a.timeout)
+	defer cancel()
+	return a.agent.StartService(ctx, req)
+}
+
+func (a *tlsAgent) StopService(req *pb.StopServiceRequest) (*pb.StopServiceResponse, error) {
+	ctx, cancel := a.withTimeout(a.timeout)
+	defer cancel()
+	return a.agent.StopService(ctx, req)
+}
+
+func (a *tlsAgent) ServiceStatus(req *pb.ServiceStatusRequest) (*pb.ServiceStatusResponse, error) {
+	ctx, cancel := a.withTimeout(a.timeout)
+	defer cancel()
+	return a.agent.ServiceStatus(ctx, req)
+}
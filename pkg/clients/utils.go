@@ -2,25 +2,43 @@ package clients
 
 import (
 	"fmt"
-	"time"
 
 	"github.com/facebook/fbthrift/thrift/lib/go/thrift"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/vesoft-inc/nebula-br/pkg/config"
 	"github.com/vesoft-inc/nebula-br/pkg/utils"
 	"github.com/vesoft-inc/nebula-go/v3/nebula"
 	"github.com/vesoft-inc/nebula-go/v3/nebula/meta"
 )
 
-const (
-	defaultTimeout = 120 * time.Second
-)
+// connOrDefault returns the default retry policy without TLS if conn is not specified
+func connOrDefault(conn *config.ConnConfig) *config.ConnConfig {
+	if conn == nil {
+		conn = &config.ConnConfig{}
+	}
+	if conn.Retry == nil {
+		c := *conn
+		c.Retry = utils.DefaultRetryPolicy()
+		conn = &c
+	}
+	return conn
+}
 
-func connect(metaAddr *nebula.HostAddr) (*meta.MetaServiceClient, error) {
+// connect connects the metad by TLS if it is enabled, the timeout of every call is limited by the socket
+func connect(metaAddr *nebula.HostAddr, conn *config.ConnConfig) (*meta.MetaServiceClient, error) {
 	log.WithField("meta address", utils.StringifyAddr(metaAddr)).Info("Try to connect meta service.")
-	timeoutOption := thrift.SocketTimeout(defaultTimeout)
-	addressOption := thrift.SocketAddr(utils.StringifyAddr(metaAddr))
-	sock, err := thrift.NewSocket(timeoutOption, addressOption)
+	var (
+		sock thrift.Transport
+		err  error
+	)
+	if conn.TLS != nil {
+		sock, err = thrift.NewSSLSocketTimeout(utils.StringifyAddr(metaAddr), conn.TLS, conn.Retry.Timeout)
+	} else {
+		timeoutOption := thrift.SocketTimeout(conn.Retry.Timeout)
+		addressOption := thrift.SocketAddr(utils.StringifyAddr(metaAddr))
+		sock, err = thrift.NewSocket(timeoutOption, addressOption)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("open socket failed: %w", err)
 	}
@@ -35,10 +53,15 @@ func connect(metaAddr *nebula.HostAddr) (*meta.MetaServiceClient, error) {
 
 	req := newVerifyClientVersionReq()
 	resp, err := client.VerifyClientVersion(req)
-	if err != nil || resp.Code != nebula.ErrorCode_SUCCEEDED {
-		log.WithError(err).WithField("addr", metaAddr).Error("Incompatible version between client and server.")
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("verify client version to %s failed: %w", utils.StringifyAddr(metaAddr), err)
+	}
+	if resp.Code != nebula.ErrorCode_SUCCEEDED {
+		log.WithField("addr", utils.StringifyAddr(metaAddr)).Error("Incompatible version between client and server.")
 		client.Close()
-		return nil, err
+		return nil, fmt.Errorf("incompatible version between client and server %s: %s",
+			utils.StringifyAddr(metaAddr), resp.Code.String())
 	}
 
 	log.WithField("meta address", utils.StringifyAddr(metaAddr)).Info("Connect meta server successfully.")
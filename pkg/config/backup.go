@@ -8,6 +8,14 @@ import (
 
 	pb "github.com/vesoft-inc/nebula-agent/pkg/proto"
 	"github.com/vesoft-inc/nebula-br/pkg/storage"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+)
+
+const (
+	flagBaseBackupName  = "base"
+	flagHostConcurrency = "host-concurrency"
+	flagManifest        = "manifest"
+	flagAllowPlaintext  = "allow-plaintext-data"
 )
 
 func AddBackupFlags(flags *pflag.FlagSet) {
@@ -16,15 +24,45 @@ func AddBackupFlags(flags *pflag.FlagSet) {
     By this option, user can specify which spaces to backup. Now this feature is still experimental.
     If not specified, will backup all spaces.
     `)
-	flags.String(FlagMetaAddr, "", "Specify meta server")
+	flags.String(FlagMetaAddr, "", "Specify meta servers, separated by comma, e.g. \"192.168.0.1:9559,192.168.0.2:9559\"")
+	flags.Int(flagConcurrency, 5, "Max concurrency for upload data")
+	flags.Int(flagHostConcurrency, 2, "Max concurrency for upload data in each storaged host")
+	flags.Bool(flagManifest, false, `Write a manifest with checksums of all the backup files, which could be checked by verify command.
+    Notice that the whole backup will be downloaded back to the local tmp dir to compute the checksums,
+    which needs the local disk as large as the backup, and local:// storage should be a shared mount.
+    `)
+	flags.Bool(flagAllowPlaintext, false, `Allow the backup with encryption key to upload the sst files in plaintext. The key encrypts
+    the backup meta files only, the sst files are uploaded by agents as they are, so the backup fails
+    with encryption key unless this is specified.
+    `)
+	AddConnFlags(flags)
 	cobra.MarkFlagRequired(flags, FlagMetaAddr)
 	cobra.MarkFlagRequired(flags, FlagStorage)
 }
 
+func AddIncrBackupFlags(flags *pflag.FlagSet) {
+	flags.String(flagBaseBackupName, "", `Specify the base backup name.
+    Only the sst files which do not exist in the base backup or its base chain will be uploaded.
+    `)
+	cobra.MarkFlagRequired(flags, flagBaseBackupName)
+}
+
 type BackupConfig struct {
 	MetaAddr string
 	Spaces   []string
 	Backend  *pb.Backend // Backend is associated with the root uri
+	Conn     *ConnConfig
+
+	EncryptionKey *utils.EncryptionKey // nil if backup is not encrypted
+	// AllowPlaintextData allows the sst files to be uploaded in plaintext when the meta files are encrypted
+	AllowPlaintextData bool
+
+	// BaseBackupName is the backup which incremental backup based on, empty for full backup
+	BaseBackupName string
+
+	Concurrency     int
+	HostConcurrency int
+	Manifest        bool
 }
 
 func (b *BackupConfig) ParseFlags(flags *pflag.FlagSet) error {
@@ -41,5 +79,44 @@ func (b *BackupConfig) ParseFlags(flags *pflag.FlagSet) error {
 	if err != nil {
 		return fmt.Errorf("parse storage flags failed: %w", err)
 	}
+	b.EncryptionKey, err = ParseEncryptionKey(flags)
+	if err != nil {
+		return err
+	}
+	b.AllowPlaintextData, err = flags.GetBool(flagAllowPlaintext)
+	if err != nil {
+		return err
+	}
+	if b.EncryptionKey != nil && !b.AllowPlaintextData {
+		return fmt.Errorf("only the backup meta files are encrypted by the key, the sst files are uploaded in plaintext, "+
+			"specify --%s to continue", flagAllowPlaintext)
+	}
+	b.Conn, err = ParseConnConfig(flags)
+	if err != nil {
+		return err
+	}
+	b.Concurrency, err = flags.GetInt(flagConcurrency)
+	if err != nil {
+		return err
+	}
+	b.HostConcurrency, err = flags.GetInt(flagHostConcurrency)
+	if err != nil {
+		return err
+	}
+	b.Manifest, err = flags.GetBool(flagManifest)
+	if err != nil {
+		return err
+	}
+	for flag, c := range map[string]int{flagConcurrency: b.Concurrency, flagHostConcurrency: b.HostConcurrency} {
+		if c <= 0 {
+			return flagError(flags, flag, fmt.Errorf("--%s should be positive, but got %d", flag, c))
+		}
+	}
+	if flags.Lookup(flagBaseBackupName) != nil {
+		b.BaseBackupName, err = flags.GetString(flagBaseBackupName)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	pb "github.com/vesoft-inc/nebula-agent/pkg/proto"
+	"github.com/vesoft-inc/nebula-br/pkg/storage"
+)
+
+func AddCheckFlags(flags *pflag.FlagSet) {
+	flags.String(FlagMetaAddr, "", "Specify meta servers, separated by comma, e.g. \"192.168.0.1:9559,192.168.0.2:9559\"")
+	AddConnFlags(flags)
+	cobra.MarkFlagRequired(flags, FlagMetaAddr)
+	cobra.MarkFlagRequired(flags, FlagStorage)
+}
+
+type CheckConfig struct {
+	MetaAddr string
+	Backend  *pb.Backend // Backend is associated with the root uri
+	Conn     *ConnConfig
+}
+
+func (c *CheckConfig) ParseFlags(flags *pflag.FlagSet) error {
+	var err error
+	c.MetaAddr, err = flags.GetString(FlagMetaAddr)
+	if err != nil {
+		return err
+	}
+	c.Backend, err = storage.ParseFromFlags(flags)
+	if err != nil {
+		return fmt.Errorf("parse storage flags failed: %w", err)
+	}
+	c.Conn, err = ParseConnConfig(flags)
+	if err != nil {
+		return err
+	}
+	return nil
+}
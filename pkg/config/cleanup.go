@@ -8,11 +8,16 @@ import (
 
 	pb "github.com/vesoft-inc/nebula-agent/pkg/proto"
 	"github.com/vesoft-inc/nebula-br/pkg/storage"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
 )
 
 func AddCleanupFlags(flags *pflag.FlagSet) {
-	flags.String(FlagMetaAddr, "", "Specify meta server")
+	flags.String(FlagMetaAddr, "", "Specify meta servers, separated by comma, e.g. \"192.168.0.1:9559,192.168.0.2:9559\"")
 	flags.String(flagBackupName, "", "Specify backup name")
+	flags.Bool(flagForce, false, `Clean up the backup even if it is the base of other incremental backups,
+    which could not be restored any more after that.
+    `)
+	AddConnFlags(flags)
 	cobra.MarkFlagRequired(flags, FlagMetaAddr)
 	cobra.MarkFlagRequired(flags, flagBackupName)
 	cobra.MarkFlagRequired(flags, FlagStorage)
@@ -22,6 +27,10 @@ type CleanupConfig struct {
 	MetaAddr   string
 	BackupName string
 	Backend    *pb.Backend // Backend is associated with the root uri
+	Conn       *ConnConfig
+	Force      bool
+
+	EncryptionKey *utils.EncryptionKey
 }
 
 func (c *CleanupConfig) ParseFlags(flags *pflag.FlagSet) error {
@@ -38,5 +47,17 @@ func (c *CleanupConfig) ParseFlags(flags *pflag.FlagSet) error {
 	if err != nil {
 		return fmt.Errorf("parse storage flags failed: %w", err)
 	}
+	c.EncryptionKey, err = ParseEncryptionKey(flags)
+	if err != nil {
+		return err
+	}
+	c.Conn, err = ParseConnConfig(flags)
+	if err != nil {
+		return err
+	}
+	c.Force, err = flags.GetBool(flagForce)
+	if err != nil {
+		return err
+	}
 	return nil
 }
@@ -1,9 +1,14 @@
 package config
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
 	"github.com/spf13/pflag"
 
 	"github.com/vesoft-inc/nebula-br/pkg/storage"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
 )
 
 const (
@@ -13,16 +18,56 @@ const (
 
 	FlagLogPath  = "log"
 	FlagLogDebug = "debug"
+	FlagReport   = "report"
 
-	flagBackupName = "name"
+	flagBackupName        = "name"
+	flagConcurrency       = "concurrency"
+	flagEncryptionKeyFile = "encryption-key-file"
 )
 
 func AddCommonFlags(flags *pflag.FlagSet) {
+	addConfigFlag(flags)
 	flags.String(FlagLogPath, "br.log", "Specify br detail log path")
 	flags.Bool(FlagLogDebug, false, "Output log in debug level or not")
+	flags.String(flagEncryptionKeyFile, "", `Specify the file of 32 bytes key, or its hex or base64 encoding, to encrypt or
+    decrypt the backup meta files (metadata only, the sst files are not encrypted), env `+utils.EncryptionKeyEnv+`
+    is used if not specified.
+    `)
 	storage.AddFlags(flags)
 }
 
+func AddReportFlags(flags *pflag.FlagSet) {
+	flags.String(FlagReport, "", `Specify the json file to write the report of this run, such as the phases,
+    hosts, bytes transferred and errors, no report is written if not specified.
+    `)
+}
+
+// ParseEncryptionKey returns nil if neither the key file nor env is specified
+func ParseEncryptionKey(flags *pflag.FlagSet) (*utils.EncryptionKey, error) {
+	file, err := flags.GetString(flagEncryptionKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []byte
+	if file != "" {
+		raw, err = ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read encryption key file %s failed: %w", file, err)
+		}
+	} else if env := os.Getenv(utils.EncryptionKeyEnv); env != "" {
+		raw = []byte(env)
+	} else {
+		return nil, nil
+	}
+
+	key, err := utils.NewEncryptionKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse encryption key failed: %w", err)
+	}
+	return key, nil
+}
+
 type NodeInfo struct {
 	Addrs   string
 	RootDir string
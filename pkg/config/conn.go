@@ -0,0 +1,163 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+)
+
+const (
+	flagCA                 = "ca"
+	flagCert               = "cert"
+	flagKey                = "key"
+	flagInsecureSkipVerify = "insecure-skip-verify"
+
+	flagRetryMaxAttempts = "retry-max-attempts"
+	flagRetryBaseBackoff = "retry-base-backoff"
+	flagRetryMaxBackoff  = "retry-max-backoff"
+	flagRetryJitter      = "retry-jitter"
+	flagRPCTimeout       = "rpc-timeout"
+	flagTransferTimeout  = "transfer-timeout"
+)
+
+// ConnConfig is how to connect the meta and agent services
+type ConnConfig struct {
+	TLS   *tls.Config // nil if TLS is not enabled
+	Retry *utils.RetryPolicy
+}
+
+// AddConnFlags adds the flags of TLS and retry policy
+func AddConnFlags(flags *pflag.FlagSet) {
+	AddTLSFlags(flags)
+
+	d := utils.DefaultRetryPolicy()
+	flags.Int(flagRetryMaxAttempts, d.MaxAttempts, "Max attempts of a meta or agent call, including the first one")
+	flags.Duration(flagRetryBaseBackoff, d.BaseBackoff, "Backoff before the first retry, doubled for each later retry")
+	flags.Duration(flagRetryMaxBackoff, d.MaxBackoff, "Max backoff between retries")
+	flags.Float64(flagRetryJitter, d.Jitter, "Randomize the backoff by the ratio, between 0 and 1")
+	flags.Duration(flagRPCTimeout, d.Timeout, `Timeout of a meta or agent call, except uploading and downloading files,
+    the agent calls are only limited when they are connected by TLS.
+    `)
+	flags.Duration(flagTransferTimeout, d.TransferTimeout, `Timeout of an agent call to upload or download files by TLS, no timeout by default,
+    since the data of a host may take hours to transfer. The timed out transfer is not retried.
+    `)
+}
+
+// NoRetry returns a copy without retry, for the calls polled by caller
+func (c *ConnConfig) NoRetry() *ConnConfig {
+	n := &ConnConfig{Retry: utils.DefaultRetryPolicy()}
+	if c != nil {
+		n.TLS = c.TLS
+		if c.Retry != nil {
+			p := *c.Retry
+			n.Retry = &p
+		}
+	}
+	n.Retry.MaxAttempts = 1
+	return n
+}
+
+func ParseConnConfig(flags *pflag.FlagSet) (*ConnConfig, error) {
+	var err error
+	c := &ConnConfig{}
+	c.TLS, err = ParseTLS(flags)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &utils.RetryPolicy{}
+	p.MaxAttempts, err = flags.GetInt(flagRetryMaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if p.MaxAttempts < 1 {
+		return nil, flagError(flags, flagRetryMaxAttempts, fmt.Errorf("--%s should be positive, but got %d", flagRetryMaxAttempts, p.MaxAttempts))
+	}
+	for flag, d := range map[string]*time.Duration{
+		flagRetryBaseBackoff: &p.BaseBackoff,
+		flagRetryMaxBackoff:  &p.MaxBackoff,
+		flagRPCTimeout:       &p.Timeout,
+		flagTransferTimeout:  &p.TransferTimeout,
+	} {
+		*d, err = flags.GetDuration(flag)
+		if err != nil {
+			return nil, err
+		}
+		if *d < 0 {
+			return nil, flagError(flags, flag, fmt.Errorf("--%s should not be negative, but got %s", flag, *d))
+		}
+	}
+	p.Jitter, err = flags.GetFloat64(flagRetryJitter)
+	if err != nil {
+		return nil, err
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return nil, flagError(flags, flagRetryJitter, fmt.Errorf("--%s should be between 0 and 1, but got %v", flagRetryJitter, p.Jitter))
+	}
+	c.Retry = p
+	return c, nil
+}
+
+func AddTLSFlags(flags *pflag.FlagSet) {
+	flags.String(flagCA, "", `Specify the CA certificate file to verify the meta and agent servers,
+    TLS is enabled when any of --ca, --cert, --key or --insecure-skip-verify is specified.
+    `)
+	flags.String(flagCert, "", "Specify the client certificate file for TLS, used with --key")
+	flags.String(flagKey, "", "Specify the client private key file for TLS, used with --cert")
+	flags.Bool(flagInsecureSkipVerify, false, "Enable TLS but skip verifying the certificates of servers, only for test")
+}
+
+// ParseTLS returns nil if TLS is not enabled
+func ParseTLS(flags *pflag.FlagSet) (*tls.Config, error) {
+	ca, err := flags.GetString(flagCA)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := flags.GetString(flagCert)
+	if err != nil {
+		return nil, err
+	}
+	key, err := flags.GetString(flagKey)
+	if err != nil {
+		return nil, err
+	}
+	skipVerify, err := flags.GetBool(flagInsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	if ca == "" && cert == "" && key == "" && !skipVerify {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{
+		InsecureSkipVerify: skipVerify,
+	}
+	if ca != "" {
+		data, err := ioutil.ReadFile(ca)
+		if err != nil {
+			return nil, fmt.Errorf("read ca file %s failed: %w", ca, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no valid certificate in ca file %s", ca)
+		}
+		cfg.RootCAs = pool
+	}
+	if (cert == "") != (key == "") {
+		return nil, fmt.Errorf("--%s and --%s should be specified together", flagCert, flagKey)
+	}
+	if cert != "" {
+		pair, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("load certificate %s and key %s failed: %w", cert, key, err)
+		}
+		cfg.Certificates = []tls.Certificate{pair}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	flagConfig = "config"
+	envPrefix  = "BR_"
+
+	// sourceAnnotation records where LoadConfig takes the value of flag from
+	sourceAnnotation = "br_source"
+)
+
+func addConfigFlag(flags *pflag.FlagSet) {
+	flags.String(flagConfig, "", `Specify a yaml file of flags, e.g. "meta: 127.0.0.1:9559" or "s3: {secret_key: xxx}",
+    env `+EnvName(flagConfig)+` is used if not specified. Every flag could also be specified by env like
+    `+EnvName("s3.secret_key")+`, the precedence is flag > env > config file.
+    `)
+}
+
+// EnvName returns the env bound to the flag, e.g. BR_S3_SECRET_KEY for s3.secret_key
+func EnvName(flag string) string {
+	name := strings.NewReplacer(".", "_", "-", "_").Replace(flag)
+	return envPrefix + strings.ToUpper(name)
+}
+
+// flatten flattens the nested keys in yaml to flag names joined by dot
+func flatten(prefix string, in map[interface{}]interface{}, out map[string]interface{}) {
+	for k, v := range in {
+		key := fmt.Sprint(k)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if m, ok := v.(map[interface{}]interface{}); ok {
+			flatten(key, m, out)
+			continue
+		}
+		out[key] = v
+	}
+}
+
+func loadConfigFile(filename string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read config file %s failed: %w", filename, err)
+	}
+	raw := make(map[interface{}]interface{})
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("unmarshal config file %s failed: %w", filename, err)
+	}
+
+	values := make(map[string]interface{})
+	flatten("", raw, values)
+	return values, nil
+}
+
+// setValues sets the flag by values from env or config file, the values
+// are appended one by one for slice flags
+func setValues(flags *pflag.FlagSet, f *pflag.Flag, values []string, source string) error {
+	for _, v := range values {
+		if err := flags.Set(f.Name, v); err != nil {
+			return fmt.Errorf("invalid value %q for flag --%s from %s: %w", v, f.Name, source, err)
+		}
+	}
+	return flags.SetAnnotation(f.Name, sourceAnnotation, []string{source})
+}
+
+// flagError appends where the value of flag comes from to the error of validating it,
+// if it is set by env or config file instead of command line.
+func flagError(flags *pflag.FlagSet, name string, err error) error {
+	f := flags.Lookup(name)
+	if f == nil || len(f.Annotations[sourceAnnotation]) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w, --%s is from %s", err, name, f.Annotations[sourceAnnotation][0])
+}
+
+func isSlice(f *pflag.Flag) bool {
+	return strings.HasSuffix(f.Value.Type(), "Slice") || strings.HasSuffix(f.Value.Type(), "Array")
+}
+
+// LoadConfig sets the flags not specified in command line by env or config file in order,
+// the keys in config file which are not flags of the command are ignored, so that one
+// config file could be shared by commands.
+func LoadConfig(flags *pflag.FlagSet) error {
+	filename := os.Getenv(EnvName(flagConfig))
+	if f := flags.Lookup(flagConfig); f != nil && f.Changed {
+		filename = f.Value.String()
+	}
+	var fileValues map[string]interface{}
+	if filename != "" {
+		var err error
+		fileValues, err = loadConfigFile(filename)
+		if err != nil {
+			return err
+		}
+	}
+
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed || f.Name == flagConfig || f.Name == "help" {
+			return
+		}
+
+		env := EnvName(f.Name)
+		if v, ok := os.LookupEnv(env); ok {
+			values := []string{v}
+			if isSlice(f) {
+				values = strings.Split(v, ",")
+			}
+			err = setValues(flags, f, values, "env "+env)
+			return
+		}
+
+		v, ok := fileValues[f.Name]
+		if !ok || v == nil {
+			return
+		}
+		var values []string
+		if list, isList := v.([]interface{}); isList {
+			if !isSlice(f) {
+				err = fmt.Errorf("invalid value for flag --%s from config file %s: a list is not allowed",
+					f.Name, filename)
+				return
+			}
+			for _, item := range list {
+				values = append(values, fmt.Sprint(item))
+			}
+		} else {
+			values = []string{fmt.Sprint(v)}
+		}
+		err = setValues(flags, f, values, fmt.Sprintf("config file %s", filename))
+	})
+	return err
+}
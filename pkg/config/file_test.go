@@ -0,0 +1,173 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+// setenv sets the env and returns the func to restore it, t.Setenv is not available in go 1.14
+func setenv(t *testing.T, key, value string) func() {
+	old, existed := os.LookupEnv(key)
+	assert.Nil(t, os.Setenv(key, value))
+	return func() {
+		if existed {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+func writeConfigFile(t *testing.T, dir, name, content string) string {
+	filename := filepath.Join(dir, name)
+	assert.Nil(t, ioutil.WriteFile(filename, []byte(content), 0644))
+	return filename
+}
+
+func newTestFlags(t *testing.T, args ...string) *pflag.FlagSet {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	addConfigFlag(flags)
+	flags.String(FlagMetaAddr, "", "")
+	flags.String("s3.secret_key", "", "")
+	flags.String("s3.region", "default", "")
+	flags.Int("concurrency", 5, "")
+	flags.StringSlice("spaces", nil, "")
+	assert.Nil(t, flags.Parse(args))
+	return flags
+}
+
+func TestLoadConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "br_config")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	filename := writeConfigFile(t, dir, "br.yaml", `
+meta: file:9559
+s3:
+  secret_key: file_secret
+  region: file_region
+concurrency: 10
+spaces: [basic, nba]
+unknown: ignored
+`)
+
+	// flag > env > config file
+	defer setenv(t, EnvName("s3.secret_key"), "env_secret")()
+	defer setenv(t, EnvName(FlagMetaAddr), "env:9559")()
+	flags := newTestFlags(t, "--config", filename, "--meta", "flag:9559")
+	assert.Nil(LoadConfig(flags))
+
+	meta, _ := flags.GetString(FlagMetaAddr)
+	assert.Equal("flag:9559", meta)
+	secret, _ := flags.GetString("s3.secret_key")
+	assert.Equal("env_secret", secret)
+	region, _ := flags.GetString("s3.region")
+	assert.Equal("file_region", region)
+	concurrency, _ := flags.GetInt("concurrency")
+	assert.Equal(10, concurrency)
+	spaces, _ := flags.GetStringSlice("spaces")
+	assert.Equal([]string{"basic", "nba"}, spaces)
+
+	// the config file is specified by env if not by flag, and slice flags from env are split by comma
+	defer setenv(t, EnvName(flagConfig), filename)()
+	defer setenv(t, EnvName("spaces"), "basic,test")()
+	flags = newTestFlags(t, "--spaces", "nba")
+	assert.Nil(LoadConfig(flags))
+	spaces, _ = flags.GetStringSlice("spaces")
+	assert.Equal([]string{"nba"}, spaces)
+	meta, _ = flags.GetString(FlagMetaAddr)
+	assert.Equal("env:9559", meta)
+
+	flags = newTestFlags(t)
+	assert.Nil(LoadConfig(flags))
+	spaces, _ = flags.GetStringSlice("spaces")
+	assert.Equal([]string{"basic", "test"}, spaces)
+	region, _ = flags.GetString("s3.region")
+	assert.Equal("file_region", region)
+}
+
+func TestLoadConfigError(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "br_config")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	defer setenv(t, EnvName(flagConfig), "")()
+
+	// the error names where the bad value comes from
+	filename := writeConfigFile(t, dir, "bad_value.yaml", "concurrency: many\n")
+	err = LoadConfig(newTestFlags(t, "--config", filename))
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "--concurrency")
+	assert.Contains(err.Error(), "config file "+filename)
+
+	filename = writeConfigFile(t, dir, "bad_list.yaml", "meta: [a:9559, b:9559]\n")
+	err = LoadConfig(newTestFlags(t, "--config", filename))
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "a list is not allowed")
+	assert.Contains(err.Error(), filename)
+
+	restore := setenv(t, EnvName("concurrency"), "many")
+	err = LoadConfig(newTestFlags(t))
+	restore()
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "--concurrency")
+	assert.Contains(err.Error(), "env "+EnvName("concurrency"))
+
+	err = LoadConfig(newTestFlags(t, "--config", filepath.Join(dir, "missing.yaml")))
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "missing.yaml")
+
+	filename = writeConfigFile(t, dir, "bad_yaml.yaml", "meta: [a:9559\n")
+	err = LoadConfig(newTestFlags(t, "--config", filename))
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "unmarshal config file "+filename)
+}
+
+func TestParseFlagsErrorSource(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "br_config")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	defer setenv(t, EnvName(flagConfig), "")()
+	filename := writeConfigFile(t, dir, "br.yaml", "storage: local:///backup\noutput: xml\n")
+
+	newFlags := func(args ...string) *pflag.FlagSet {
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		AddCommonFlags(flags)
+		AddShowFlags(flags)
+		AddConnFlags(flags)
+		assert.Nil(flags.Parse(args))
+		return flags
+	}
+
+	// the validation error names the config file or env the bad value comes from
+	flags := newFlags("--config", filename)
+	assert.Nil(LoadConfig(flags))
+	err = (&ShowConfig{}).ParseFlags(flags)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "invalid output format xml")
+	assert.Contains(err.Error(), "--output is from config file "+filename)
+
+	restore := setenv(t, EnvName(flagRetryJitter), "2")
+	flags = newFlags("--config", filename)
+	assert.Nil(LoadConfig(flags))
+	restore()
+	_, err = ParseConnConfig(flags)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "--retry-jitter is from env "+EnvName(flagRetryJitter))
+
+	// no source is appended for the value from command line
+	flags = newFlags("--config", filename, "--output", "csv", "--name", "BACKUP_2022_03_01_10_00_00")
+	assert.Nil(LoadConfig(flags))
+	err = (&ShowConfig{}).ParseFlags(flags)
+	assert.NotNil(err)
+	assert.NotContains(err.Error(), " is from ")
+}
@@ -0,0 +1,230 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+	"github.com/vesoft-inc/nebula-go/v3/nebula"
+	"github.com/vesoft-inc/nebula-go/v3/nebula/meta"
+)
+
+// HostMapping maps a storaged in backup to a storaged in the target cluster
+type HostMapping struct {
+	From string `yaml:"from" json:"from"`
+	To   string `yaml:"to" json:"to"`
+	// Paths[i] is the target data path index for the backup data path i,
+	// same index will be used if not specified.
+	Paths []int `yaml:"paths,omitempty" json:"paths,omitempty"`
+}
+
+// HostMap describes how to restore a backup to a cluster with different topology.
+// It could be written in yaml or json, for example:
+//
+//	hosts:
+//	  - from: 192.168.8.1:9779
+//	    to: 10.0.0.1:9779
+//	    paths: [1, 0]
+type HostMap struct {
+	Hosts []*HostMapping `yaml:"hosts" json:"hosts"`
+}
+
+func LoadHostMap(filename string) (*HostMap, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read host map file %s failed: %w", filename, err)
+	}
+
+	// json is a subset of yaml, so one parser is enough
+	m := &HostMap{}
+	if err = yaml.UnmarshalStrict(data, m); err != nil {
+		return nil, fmt.Errorf("parse host map file %s failed: %w", filename, err)
+	}
+
+	if err = m.validate(); err != nil {
+		return nil, fmt.Errorf("invalid host map file %s: %w", filename, err)
+	}
+	return m, nil
+}
+
+func (m *HostMap) validate() error {
+	if len(m.Hosts) == 0 {
+		return fmt.Errorf("no host mapping")
+	}
+
+	froms := make(map[string]bool)
+	tos := make(map[string]bool)
+	for _, h := range m.Hosts {
+		if _, err := utils.ParseAddr(h.From); err != nil {
+			return fmt.Errorf("parse backup host failed: %w", err)
+		}
+		if _, err := utils.ParseAddr(h.To); err != nil {
+			return fmt.Errorf("parse target host failed: %w", err)
+		}
+		if froms[h.From] {
+			return fmt.Errorf("backup host %s is mapped more than once", h.From)
+		}
+		if tos[h.To] {
+			return fmt.Errorf("target host %s is mapped more than once", h.To)
+		}
+		froms[h.From] = true
+		tos[h.To] = true
+
+		paths := make(map[int]bool)
+		for _, p := range h.Paths {
+			if p < 0 {
+				return fmt.Errorf("negative data path index %d for %s", p, h.From)
+			}
+			if paths[p] {
+				return fmt.Errorf("target data path %d of %s is mapped more than once", p, h.To)
+			}
+			paths[p] = true
+		}
+	}
+	return nil
+}
+
+// Get return the mapping of the given backup host, nil if not found
+func (m *HostMap) Get(from string) *HostMapping {
+	for _, h := range m.Hosts {
+		if h.From == from {
+			return h
+		}
+	}
+	return nil
+}
+
+// StoragePair indicates where the data of a backup storaged should be restored to
+type StoragePair struct {
+	From  *nebula.HostAddr
+	To    *meta.ServiceInfo
+	Paths []int // Paths[i] is the target data path index of backup data path i
+}
+
+// backupStorages returns the storaged hosts in backup and their data path count
+func backupStorages(backup *meta.BackupMeta) (map[string]*nebula.HostAddr, map[string]int) {
+	addrs := make(map[string]*nebula.HostAddr)
+	paths := make(map[string]int)
+	for _, sb := range backup.GetSpaceBackups() {
+		for _, hb := range sb.GetHostBackups() {
+			addrStr := utils.StringifyAddr(hb.GetHost())
+			if _, ok := addrs[addrStr]; !ok {
+				addrs[addrStr] = hb.GetHost()
+				paths[addrStr] = len(hb.GetCheckpoints())
+			}
+		}
+	}
+	return addrs, paths
+}
+
+// PairStorages pairs the storaged in backup with the storages in cluster, by the host map
+// if it is not nil, otherwise by sorting their addresses. Restore and validate should
+// always pair them by it, so that they agree on where the data goes.
+func PairStorages(backup *meta.BackupMeta, storages []*meta.ServiceInfo, hostMap *HostMap) ([]*StoragePair, error) {
+	if hostMap != nil {
+		return pairByHostMap(backup, storages, hostMap)
+	}
+	return pairBySort(backup, storages)
+}
+
+// StorageMap returns the storaged address map from backup to cluster of the pairs
+func StorageMap(pairs []*StoragePair) map[string]string {
+	storageMap := make(map[string]string)
+	for _, pair := range pairs {
+		storageMap[utils.StringifyAddr(pair.From)] = utils.StringifyAddr(pair.To.GetAddr())
+	}
+	return storageMap
+}
+
+// pairBySort pairs the storaged in backup and cluster by sorting their addresses,
+// the physical topology should be consistent.
+func pairBySort(backup *meta.BackupMeta, storages []*meta.ServiceInfo) ([]*StoragePair, error) {
+	// current cluster storage service list
+	currList := make([]*meta.ServiceInfo, len(storages))
+	copy(currList, storages)
+	sort.Slice(currList, func(i, j int) bool {
+		return utils.LessAddr(currList[i].GetAddr(), currList[j].GetAddr())
+	})
+
+	// previous backup storage service list
+	prevMap, _ := backupStorages(backup)
+	prevList := make([]*nebula.HostAddr, 0, len(prevMap))
+	for _, addr := range prevMap {
+		prevList = append(prevList, addr)
+	}
+	sort.Slice(prevList, func(i, j int) bool {
+		return utils.LessAddr(prevList[i], prevList[j])
+	})
+	if len(prevList) != len(currList) {
+		return nil, fmt.Errorf("storage count of cluster %d is not equal to backup %d, the host map should be specified",
+			len(currList), len(prevList))
+	}
+
+	pairs := make([]*StoragePair, 0, len(currList))
+	for idx, s := range currList {
+		paths := make([]int, len(s.GetDir().GetData()))
+		for i := range paths {
+			paths[i] = i
+		}
+		pairs = append(pairs, &StoragePair{From: prevList[idx], To: s, Paths: paths})
+	}
+	return pairs, nil
+}
+
+// pairByHostMap pairs the storaged in backup and cluster by the user specified host map,
+// every storaged in backup should be mapped exactly once.
+func pairByHostMap(backup *meta.BackupMeta, storages []*meta.ServiceInfo, hostMap *HostMap) ([]*StoragePair, error) {
+	prevMap, prevPaths := backupStorages(backup)
+	for addrStr := range prevMap {
+		if hostMap.Get(addrStr) == nil {
+			return nil, fmt.Errorf("storaged %s in backup is not mapped in host map", addrStr)
+		}
+	}
+
+	currMap := make(map[string]*meta.ServiceInfo)
+	for _, s := range storages {
+		currMap[utils.StringifyAddr(s.GetAddr())] = s
+	}
+
+	pairs := make([]*StoragePair, 0, len(hostMap.Hosts))
+	for _, h := range hostMap.Hosts {
+		from, ok := prevMap[h.From]
+		if !ok {
+			return nil, fmt.Errorf("storaged %s in host map does not exist in backup", h.From)
+		}
+		to, ok := currMap[h.To]
+		if !ok {
+			return nil, fmt.Errorf("storaged %s in host map does not exist in cluster", h.To)
+		}
+
+		paths := h.Paths
+		if len(paths) == 0 {
+			paths = make([]int, prevPaths[h.From])
+			for i := range paths {
+				paths[i] = i
+			}
+		}
+		if len(paths) != prevPaths[h.From] {
+			return nil, fmt.Errorf("storaged %s has %d data paths in backup, but %d are mapped",
+				h.From, prevPaths[h.From], len(paths))
+		}
+		for _, p := range paths {
+			if p >= len(to.GetDir().GetData()) {
+				return nil, fmt.Errorf("storaged %s only has %d data paths, could not map to path %d",
+					h.To, len(to.GetDir().GetData()), p)
+			}
+		}
+
+		pairs = append(pairs, &StoragePair{From: from, To: to, Paths: paths})
+		delete(currMap, h.To)
+	}
+
+	for addrStr := range currMap {
+		log.WithField("addr", addrStr).Warn("Storaged in cluster is not mapped, it will have no data after restore.")
+	}
+	return pairs, nil
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	pb "github.com/vesoft-inc/nebula-agent/pkg/proto"
+	"github.com/vesoft-inc/nebula-br/pkg/storage"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+)
+
+const (
+	flagKeepLast    = "keep-last"
+	flagKeepWithin  = "keep-within"
+	flagKeepDaily   = "keep-daily"
+	flagKeepWeekly  = "keep-weekly"
+	flagKeepMonthly = "keep-monthly"
+)
+
+func AddPruneFlags(flags *pflag.FlagSet) {
+	flags.String(FlagMetaAddr, "", `Specify meta servers, separated by comma, e.g. "192.168.0.1:9559,192.168.0.2:9559",
+    only required for local storage, whose backups are removed from the cluster hosts by agents.
+    `)
+	flags.Int(flagKeepLast, 0, "Keep the latest n backups")
+	flags.String(flagKeepWithin, "", "Keep the backups created within the duration, e.g. 30d, 2w, 12h")
+	flags.Int(flagKeepDaily, 0, "Keep the latest backup of each day for the latest n days which have backups")
+	flags.Int(flagKeepWeekly, 0, "Keep the latest backup of each week for the latest n weeks which have backups")
+	flags.Int(flagKeepMonthly, 0, "Keep the latest backup of each month for the latest n months which have backups")
+	flags.Bool(flagDryRun, false, "Only print the backups to keep and delete, without deleting them")
+	AddConnFlags(flags)
+
+	cobra.MarkFlagRequired(flags, FlagStorage)
+}
+
+type PruneConfig struct {
+	MetaAddr string
+	Backend  *pb.Backend // Backend is associated with the root uri
+	Conn     *ConnConfig
+	Policy   *utils.RetentionPolicy
+	DryRun   bool
+
+	EncryptionKey *utils.EncryptionKey
+}
+
+func (p *PruneConfig) ParseFlags(flags *pflag.FlagSet) error {
+	var err error
+	p.MetaAddr, err = flags.GetString(FlagMetaAddr)
+	if err != nil {
+		return err
+	}
+	p.Backend, err = storage.ParseFromFlags(flags)
+	if err != nil {
+		return fmt.Errorf("parse storage flags failed: %w", err)
+	}
+	if p.Backend.GetLocal() != nil && p.MetaAddr == "" {
+		return fmt.Errorf("--%s should be specified for local storage, the backups are in the cluster hosts", FlagMetaAddr)
+	}
+	p.EncryptionKey, err = ParseEncryptionKey(flags)
+	if err != nil {
+		return err
+	}
+	p.Conn, err = ParseConnConfig(flags)
+	if err != nil {
+		return err
+	}
+	p.DryRun, err = flags.GetBool(flagDryRun)
+	if err != nil {
+		return err
+	}
+
+	p.Policy = &utils.RetentionPolicy{}
+	for flag, v := range map[string]*int{
+		flagKeepLast:    &p.Policy.KeepLast,
+		flagKeepDaily:   &p.Policy.KeepDaily,
+		flagKeepWeekly:  &p.Policy.KeepWeekly,
+		flagKeepMonthly: &p.Policy.KeepMonthly,
+	} {
+		*v, err = flags.GetInt(flag)
+		if err != nil {
+			return err
+		}
+		if *v < 0 {
+			return flagError(flags, flag, fmt.Errorf("--%s should not be negative, but got %d", flag, *v))
+		}
+	}
+	within, err := flags.GetString(flagKeepWithin)
+	if err != nil {
+		return err
+	}
+	if within != "" {
+		p.Policy.KeepWithin, err = utils.ParseRetentionDuration(within)
+		if err != nil {
+			return flagError(flags, flagKeepWithin, fmt.Errorf("parse --%s failed: %w", flagKeepWithin, err))
+		}
+	}
+
+	// an empty policy will delete all backups, which is never expected
+	if p.Policy.Empty() {
+		return fmt.Errorf("at least one of --%s, --%s, --%s, --%s, --%s should be specified",
+			flagKeepLast, flagKeepWithin, flagKeepDaily, flagKeepWeekly, flagKeepMonthly)
+	}
+	return nil
+}
@@ -2,32 +2,129 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
 	pb "github.com/vesoft-inc/nebula-agent/pkg/proto"
 	"github.com/vesoft-inc/nebula-br/pkg/storage"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
 )
 
 const (
-	flagConcurrency = "concurrency"
+	flagHostMap = "host-map"
+	flagDryRun  = "dry-run"
+	FlagJournal = "journal"
+	flagSuffix  = "suffix"
+
+	flagPaceRate     = "pace-rate"
+	flagHostPaceRate = "host-pace-rate"
+	flagReadyTimeout = "ready-timeout"
+	flagValidate     = "validate"
+	flagForce        = "force"
+	flagYes          = "yes"
 )
 
+func defaultJournalPath(backupName string) string {
+	return fmt.Sprintf("restore_%s.journal", backupName)
+}
+
 func AddRestoreFlags(flags *pflag.FlagSet) {
-	flags.String(FlagMetaAddr, "", "Specify meta server")
+	flags.String(FlagMetaAddr, "", "Specify meta servers, separated by comma, e.g. \"192.168.0.1:9559,192.168.0.2:9559\"")
 	flags.String(flagBackupName, "", "Specify backup name")
-	flags.Int(flagConcurrency, 5, "Max concurrency for download data") // TODO(spw): not use now
+	flags.String(flagHostMap, "", `Specify a yaml or json file which maps storaged hosts and data paths
+    in backup to the target cluster, used when the cluster topology is different from the backup.
+    If not specified, hosts will be paired by sorting their addresses.
+    `)
+	flags.String(FlagJournal, "", `Specify the local file to record restore progress, which is used to resume
+    or rollback an interrupted restore, default is restore_{backup name}.journal in current dir.
+    The journal is also kept in backup dir, and will be used if the local one does not exist.
+    `)
+	addDownloadFlags(flags)
 
 	cobra.MarkFlagRequired(flags, FlagMetaAddr)
 	cobra.MarkFlagRequired(flags, FlagStorage)
 	cobra.MarkFlagRequired(flags, flagBackupName)
 }
 
+// AddResumeFlags adds the flags of resume, which takes the cluster topology, the storage pairs
+// and the storage uri from journal, so only the journal is required.
+func AddResumeFlags(flags *pflag.FlagSet) {
+	flags.String(FlagMetaAddr, "", `Specify meta servers, separated by comma, e.g. "192.168.0.1:9559,192.168.0.2:9559",
+    only needed when the spaces to restore have not been dropped.
+    `)
+	flags.String(flagBackupName, "", "Specify backup name, which is checked against the journal if specified")
+	flags.String(FlagJournal, "", `Specify the local journal file of the interrupted restore`)
+	addDownloadFlags(flags)
+
+	// the storage is recorded in journal, the flag is only to override it
+	flags.SetAnnotation(FlagStorage, cobra.BashCompOneRequiredFlag, []string{"false"})
+	cobra.MarkFlagRequired(flags, FlagJournal)
+}
+
+func addDownloadFlags(flags *pflag.FlagSet) {
+	flags.Int(flagConcurrency, 5, "Max concurrency for download data")
+	flags.String(flagPaceRate, "", `Pace the starts of downloading storage dirs by their sizes to the average rate in total,
+    e.g. 200MB/s, not paced by default. Every dir is still downloaded by agent at full speed, so the peak
+    rate is not limited. The sizes are read from manifests, so the backup should be taken with --manifest.
+    `)
+	flags.String(flagHostPaceRate, "", "Pace the starts of downloading storage dirs in each host, e.g. 100MB/s")
+	flags.Duration(flagReadyTimeout, 10*time.Minute, `Max time to wait for the services to be ready after started, the restore fails if
+    they are not serving in time, 0 means not to wait.
+    `)
+	flags.Bool(flagValidate, false, `Validate the restored spaces, partitions, replicas and storage hosts against the
+    backup meta after the cluster is ready, the restore fails if any is not consistent.
+    `)
+	AddConnFlags(flags)
+}
+
+func AddFullRestoreFlags(flags *pflag.FlagSet) {
+	flags.Bool(flagDryRun, false, "Only check the backup and print the restore plan, without changing the cluster")
+	flags.StringArray(FlagSpaces, nil, `Specify the spaces in backup to restore, only these spaces will be dropped and replaced,
+    and the other spaces in cluster are kept. If not specified, all the spaces in backup will be restored.
+    `)
+	flags.Bool(flagForce, false, `Allow restoring some spaces by --spaces from a backup of the entire cluster, the meta of
+    which reverts the users, roles, configs and the schema of all the other spaces in cluster to the backup.
+    `)
+}
+
+func AddRollbackFlags(flags *pflag.FlagSet) {
+	flags.String(FlagMetaAddr, "", `Specify meta servers, separated by comma, e.g. "192.168.0.1:9559,192.168.0.2:9559",
+    it is required by --suffix, and used by journal if the spaces have not been dropped.
+    `)
+	flags.String(flagBackupName, "", "Specify backup name, to rollback the restore of it by journal")
+	flags.String(FlagJournal, "", `Specify the local restore journal file, default is restore_{backup name}.journal
+    in current dir, the one kept in backup dir will be used if the local one does not exist.
+    `)
+	flags.String(flagSuffix, "", `Specify the suffix of the origin data dirs to move back, e.g. _old_1639000000,
+    or "latest" to use the latest one recorded in restore journals and existing in the cluster.
+    Restore journal is used instead if not specified.
+    `)
+	flags.Bool(flagYes, false, `Confirm to remove the current data dirs and move the origin ones back, otherwise
+    only the dirs to move back are printed.
+    `)
+	AddConnFlags(flags)
+}
+
 type RestoreConfig struct {
 	MetaAddr   string
 	BackupName string
 	Backend    *pb.Backend
+	HostMap    *HostMap // nil if not specified
+	Conn       *ConnConfig
+	Spaces     []string // empty means all the spaces in backup
+	Force      bool     // allow restoring some spaces from a full-cluster backup
+
+	JournalPath   string
+	EncryptionKey *utils.EncryptionKey
+
+	Concurrency  int
+	PaceRate     int64 // bytes per second to pace the dir downloads, 0 means not paced
+	HostPaceRate int64
+	DryRun       bool
+	ReadyTimeout time.Duration
+	Validate     bool
 }
 
 func (r *RestoreConfig) ParseFlags(flags *pflag.FlagSet) error {
@@ -44,5 +141,135 @@ func (r *RestoreConfig) ParseFlags(flags *pflag.FlagSet) error {
 	if err != nil {
 		return fmt.Errorf("parse storage flags failed: %w", err)
 	}
+	r.EncryptionKey, err = ParseEncryptionKey(flags)
+	if err != nil {
+		return err
+	}
+	r.Conn, err = ParseConnConfig(flags)
+	if err != nil {
+		return err
+	}
+	r.Concurrency, err = flags.GetInt(flagConcurrency)
+	if err != nil {
+		return err
+	}
+	if r.Concurrency <= 0 {
+		return flagError(flags, flagConcurrency, fmt.Errorf("--%s should be positive, but got %d", flagConcurrency, r.Concurrency))
+	}
+	for flag, rate := range map[string]*int64{flagPaceRate: &r.PaceRate, flagHostPaceRate: &r.HostPaceRate} {
+		s, err := flags.GetString(flag)
+		if err != nil {
+			return err
+		}
+		*rate, err = utils.ParseRate(s)
+		if err != nil {
+			return flagError(flags, flag, fmt.Errorf("parse --%s failed: %w", flag, err))
+		}
+	}
+	r.ReadyTimeout, err = flags.GetDuration(flagReadyTimeout)
+	if err != nil {
+		return err
+	}
+	if r.ReadyTimeout < 0 {
+		return flagError(flags, flagReadyTimeout, fmt.Errorf("--%s should not be negative, but got %s", flagReadyTimeout, r.ReadyTimeout))
+	}
+	r.Validate, err = flags.GetBool(flagValidate)
+	if err != nil {
+		return err
+	}
+	if flags.Lookup(flagDryRun) != nil {
+		r.DryRun, err = flags.GetBool(flagDryRun)
+		if err != nil {
+			return err
+		}
+	}
+	if flags.Lookup(FlagSpaces) != nil {
+		r.Spaces, err = flags.GetStringArray(FlagSpaces)
+		if err != nil {
+			return err
+		}
+	}
+	if flags.Lookup(flagForce) != nil {
+		r.Force, err = flags.GetBool(flagForce)
+		if err != nil {
+			return err
+		}
+	}
+	r.JournalPath, err = flags.GetString(FlagJournal)
+	if err != nil {
+		return err
+	}
+	if r.JournalPath == "" {
+		r.JournalPath = defaultJournalPath(r.BackupName)
+	}
+	if flags.Lookup(flagHostMap) == nil {
+		return nil
+	}
+	hostMapFile, err := flags.GetString(flagHostMap)
+	if err != nil {
+		return err
+	}
+	if hostMapFile != "" {
+		r.HostMap, err = LoadHostMap(hostMapFile)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type RollbackConfig struct {
+	MetaAddr    string
+	BackupName  string
+	Backend     *pb.Backend // nil if --storage is not specified
+	Conn        *ConnConfig
+	JournalPath string
+	Suffix      string // empty means by journal
+	Yes         bool   // confirmed to remove the current data dirs
+}
+
+func (r *RollbackConfig) ParseFlags(flags *pflag.FlagSet) error {
+	var err error
+	r.MetaAddr, err = flags.GetString(FlagMetaAddr)
+	if err != nil {
+		return err
+	}
+	r.BackupName, err = flags.GetString(flagBackupName)
+	if err != nil {
+		return err
+	}
+	uri, err := flags.GetString(FlagStorage)
+	if err != nil {
+		return err
+	}
+	if uri != "" {
+		r.Backend, err = storage.ParseFromFlags(flags)
+		if err != nil {
+			return fmt.Errorf("parse storage flags failed: %w", err)
+		}
+	}
+	r.JournalPath, err = flags.GetString(FlagJournal)
+	if err != nil {
+		return err
+	}
+	r.Suffix, err = flags.GetString(flagSuffix)
+	if err != nil {
+		return err
+	}
+	r.Yes, err = flags.GetBool(flagYes)
+	if err != nil {
+		return err
+	}
+	r.Conn, err = ParseConnConfig(flags)
+	if err != nil {
+		return err
+	}
+
+	if r.Suffix == "" && r.JournalPath == "" {
+		if r.BackupName == "" {
+			return fmt.Errorf("either --%s, --%s or --%s should be specified", flagBackupName, FlagJournal, flagSuffix)
+		}
+		r.JournalPath = defaultJournalPath(r.BackupName)
+	}
 	return nil
 }
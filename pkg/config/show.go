@@ -7,10 +7,29 @@ import (
 	pb "github.com/vesoft-inc/nebula-agent/pkg/proto"
 
 	"github.com/vesoft-inc/nebula-br/pkg/storage"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
 )
 
+const (
+	flagOutput = "output"
+
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+	OutputCSV   = "csv"
+)
+
+func AddShowFlags(flags *pflag.FlagSet) {
+	flags.String(flagOutput, OutputTable, "Specify the output format, optional: table, json, yaml, csv")
+	flags.String(flagBackupName, "", "Specify backup name to show its detail, csv output is not supported for it")
+}
+
 type ShowConfig struct {
-	Backend *pb.Backend
+	Backend    *pb.Backend
+	Output     string
+	BackupName string // show detail of the backup if specified
+
+	EncryptionKey *utils.EncryptionKey
 }
 
 func (s *ShowConfig) ParseFlags(flags *pflag.FlagSet) error {
@@ -19,5 +38,27 @@ func (s *ShowConfig) ParseFlags(flags *pflag.FlagSet) error {
 		return fmt.Errorf("parse storage flags failed: %w", err)
 	}
 	s.Backend = backend
+	s.EncryptionKey, err = ParseEncryptionKey(flags)
+	if err != nil {
+		return err
+	}
+
+	s.Output, err = flags.GetString(flagOutput)
+	if err != nil {
+		return err
+	}
+	switch s.Output {
+	case OutputTable, OutputJSON, OutputYAML, OutputCSV:
+	default:
+		return flagError(flags, flagOutput, fmt.Errorf("invalid output format %s, optional: table, json, yaml, csv", s.Output))
+	}
+
+	s.BackupName, err = flags.GetString(flagBackupName)
+	if err != nil {
+		return err
+	}
+	if s.BackupName != "" && s.Output == OutputCSV {
+		return fmt.Errorf("csv output is not supported when showing the detail of backup %s", s.BackupName)
+	}
 	return nil
 }
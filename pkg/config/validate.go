@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	pb "github.com/vesoft-inc/nebula-agent/pkg/proto"
+	"github.com/vesoft-inc/nebula-br/pkg/storage"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+)
+
+func AddValidateFlags(flags *pflag.FlagSet) {
+	flags.String(FlagMetaAddr, "", "Specify meta servers, separated by comma, e.g. \"192.168.0.1:9559,192.168.0.2:9559\"")
+	flags.String(flagBackupName, "", "Specify the backup name which the cluster is restored from")
+	flags.String(flagHostMap, "", `Specify the host map file used in restore, if not specified, hosts will be
+    paired by sorting their addresses as restore does.
+    `)
+	flags.StringArray(FlagSpaces, nil, `Specify the spaces restored by --spaces of restore full, only these spaces in backup
+    are validated. If not specified, all the spaces in backup are validated.
+    `)
+	AddConnFlags(flags)
+
+	cobra.MarkFlagRequired(flags, FlagMetaAddr)
+	cobra.MarkFlagRequired(flags, FlagStorage)
+	cobra.MarkFlagRequired(flags, flagBackupName)
+}
+
+type ValidateConfig struct {
+	MetaAddr      string
+	BackupName    string
+	Backend       *pb.Backend
+	HostMap       *HostMap // nil if not specified
+	Conn          *ConnConfig
+	EncryptionKey *utils.EncryptionKey
+	Spaces        []string // empty means all the spaces in backup
+}
+
+func (v *ValidateConfig) ParseFlags(flags *pflag.FlagSet) error {
+	var err error
+	v.MetaAddr, err = flags.GetString(FlagMetaAddr)
+	if err != nil {
+		return err
+	}
+	v.BackupName, err = flags.GetString(flagBackupName)
+	if err != nil {
+		return err
+	}
+	v.Backend, err = storage.ParseFromFlags(flags)
+	if err != nil {
+		return fmt.Errorf("parse storage flags failed: %w", err)
+	}
+	v.EncryptionKey, err = ParseEncryptionKey(flags)
+	if err != nil {
+		return err
+	}
+	v.Conn, err = ParseConnConfig(flags)
+	if err != nil {
+		return err
+	}
+	v.Spaces, err = flags.GetStringArray(FlagSpaces)
+	if err != nil {
+		return err
+	}
+	hostMapFile, err := flags.GetString(flagHostMap)
+	if err != nil {
+		return err
+	}
+	if hostMapFile != "" {
+		v.HostMap, err = LoadHostMap(hostMapFile)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	pb "github.com/vesoft-inc/nebula-agent/pkg/proto"
+	"github.com/vesoft-inc/nebula-br/pkg/storage"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+)
+
+func AddVerifyFlags(flags *pflag.FlagSet) {
+	flags.String(flagBackupName, "", "Specify backup name")
+	cobra.MarkFlagRequired(flags, flagBackupName)
+	cobra.MarkFlagRequired(flags, FlagStorage)
+}
+
+type VerifyConfig struct {
+	BackupName string
+	Backend    *pb.Backend // Backend is associated with the root uri
+
+	EncryptionKey *utils.EncryptionKey
+}
+
+func (v *VerifyConfig) ParseFlags(flags *pflag.FlagSet) error {
+	var err error
+	v.BackupName, err = flags.GetString(flagBackupName)
+	if err != nil {
+		return err
+	}
+	v.Backend, err = storage.ParseFromFlags(flags)
+	if err != nil {
+		return fmt.Errorf("parse storage flags failed: %w", err)
+	}
+	v.EncryptionKey, err = ParseEncryptionKey(flags)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package log
 
 import (
+	"fmt"
 	"io"
 	"os"
 
@@ -10,6 +11,8 @@ import (
 	"github.com/vesoft-inc/nebula-br/pkg/config"
 )
 
+var logFile *os.File
+
 func SetLog(flags *pflag.FlagSet) error {
 	logrus.SetFormatter(&logrus.JSONFormatter{
 		TimestampFormat: "2006-01-02T15:04:05.000Z",
@@ -38,8 +41,49 @@ func SetLog(flags *pflag.FlagSet) error {
 		return err
 	}
 
+	logFile = file
 	mw := io.MultiWriter(os.Stdout, file)
 	logrus.SetOutput(mw)
 
 	return nil
 }
+
+// DisableStdout writes log to the log file only, used when the stdout should be parsed by machine
+func DisableStdout() {
+	if logFile != nil {
+		logrus.SetOutput(logFile)
+	}
+}
+
+// stderrHook writes the warnings and errors to stderr, the line of progress view is cleared
+// before them, and will be rendered again in the next refresh.
+type stderrHook struct {
+	out       io.Writer
+	formatter logrus.Formatter
+}
+
+func (h *stderrHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel, logrus.WarnLevel}
+}
+
+func (h *stderrHook) Fire(entry *logrus.Entry) error {
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(h.out, "\r\033[K%s", line)
+	return err
+}
+
+// StderrForWarn writes log to the log file only, except the warnings and errors which are
+// written to stderr too, used when the progress view is rendered in stdout
+func StderrForWarn() {
+	if logFile == nil {
+		return
+	}
+	logrus.SetOutput(logFile)
+	logrus.AddHook(&stderrHook{
+		out:       os.Stderr,
+		formatter: &logrus.TextFormatter{DisableTimestamp: true},
+	})
+}
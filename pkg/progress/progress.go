@@ -0,0 +1,295 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+)
+
+const (
+	terminalInterval = 500 * time.Millisecond
+	eventInterval    = 10 * time.Second
+)
+
+// Terminal tells whether the stdout is a terminal, in which the progress view
+// is rendered, otherwise the progress is reported by log events.
+func Terminal() bool {
+	fi, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Record is the result of a finished or running phase
+type Record struct {
+	Name      string    `json:"name"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Tasks     int       `json:"tasks,omitempty"`
+	DoneTasks int       `json:"done_tasks,omitempty"`
+	Bytes     int64     `json:"bytes,omitempty"` // 0 if unknown
+	DoneBytes int64     `json:"done_bytes,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+type phase struct {
+	*Record
+	index    int // 1-based index in all phases
+	lastTask string
+}
+
+// Progress tracks the phases of an operation, such as backup or restore, and
+// the tasks and bytes transferred in the current phase. All the methods could
+// be called on nil Progress, which reports nothing.
+type Progress struct {
+	op       string
+	phases   []string
+	out      io.Writer
+	terminal bool
+
+	mu      sync.Mutex
+	start   time.Time
+	cur     *phase
+	records []*Record
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New starts to report the progress of op, which consists of the given phases in order
+func New(op string, phases []string) *Progress {
+	return newProgress(op, phases, os.Stdout, Terminal())
+}
+
+func newProgress(op string, phases []string, out io.Writer, terminal bool) *Progress {
+	p := &Progress{
+		op:       op,
+		phases:   phases,
+		out:      out,
+		terminal: terminal,
+		start:    time.Now(),
+		stop:     make(chan struct{}),
+	}
+
+	interval := eventInterval
+	if p.terminal {
+		interval = terminalInterval
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.mu.Lock()
+				p.report()
+				p.mu.Unlock()
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+	return p
+}
+
+// Phase finishes the current phase and starts the next one
+func (p *Progress) Phase(name string) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.finishPhase()
+
+	index := len(p.phases)
+	for i, n := range p.phases {
+		if n == name {
+			index = i + 1
+			break
+		}
+	}
+	p.cur = &phase{
+		Record: &Record{Name: name, Start: time.Now()},
+		index:  index,
+	}
+	p.records = append(p.records, p.cur.Record)
+	p.report()
+}
+
+// Total sets the amount of work in current phase, bytes is 0 if unknown
+func (p *Progress) Total(tasks int, bytes int64) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cur == nil {
+		return
+	}
+	p.cur.Tasks = tasks
+	p.cur.Bytes = bytes
+}
+
+// Done marks a task in current phase finished with the bytes it transferred
+func (p *Progress) Done(task string, bytes int64) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cur == nil {
+		return
+	}
+	p.cur.DoneTasks++
+	p.cur.DoneBytes += bytes
+	p.cur.lastTask = task
+	if !p.terminal {
+		p.entry().WithField("task", task).Info("Progress task finished.")
+	}
+}
+
+// Finish stops reporting, and reports the result of the last phase
+func (p *Progress) Finish(err error) {
+	if p == nil {
+		return
+	}
+
+	close(p.stop)
+	p.wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil && p.cur != nil {
+		p.cur.End = time.Now()
+		p.cur.Error = err.Error()
+		if p.terminal {
+			fmt.Fprintf(p.out, "\r\033[K%s failed\n", p.line())
+		} else {
+			p.entry().WithError(err).Info("Progress phase failed.")
+		}
+		p.cur = nil
+		return
+	}
+	p.finishPhase()
+}
+
+func (p *Progress) finishPhase() {
+	c := p.cur
+	if c == nil {
+		return
+	}
+	c.End = time.Now()
+	c.DoneTasks = c.Tasks
+	if c.Bytes > 0 {
+		c.DoneBytes = c.Bytes
+	}
+	if p.terminal {
+		fmt.Fprintf(p.out, "\r\033[K%s done in %s\n", p.title(), c.End.Sub(c.Start).Round(time.Second))
+	} else {
+		p.entry().Info("Progress phase finished.")
+	}
+	p.cur = nil
+}
+
+func (p *Progress) report() {
+	if p.cur == nil {
+		return
+	}
+	if p.terminal {
+		fmt.Fprintf(p.out, "\r\033[K%s", p.line())
+		return
+	}
+	p.entry().Info("Progress.")
+}
+
+// eta estimates the remaining time of current phase by bytes, or by tasks if bytes is unknown
+func (p *Progress) eta() time.Duration {
+	c := p.cur
+	elapsed := time.Since(c.Start)
+	done, total := float64(c.DoneTasks), float64(c.Tasks)
+	if c.Bytes > 0 {
+		done, total = float64(c.DoneBytes), float64(c.Bytes)
+	}
+	if done <= 0 || done >= total {
+		return 0
+	}
+	return time.Duration(float64(elapsed) * (total - done) / done)
+}
+
+// capitalize upper cases the first letter of s, the op names are single ascii words
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+func (p *Progress) title() string {
+	return fmt.Sprintf("%s [%d/%d] %s", capitalize(p.op), p.cur.index, len(p.phases), p.cur.Name)
+}
+
+func (p *Progress) line() string {
+	c := p.cur
+	parts := []string{p.title()}
+	if c.Tasks > 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d tasks", c.DoneTasks, c.Tasks))
+	}
+	if c.Bytes > 0 {
+		parts = append(parts, fmt.Sprintf("%s/%s", utils.FormatSize(c.DoneBytes), utils.FormatSize(c.Bytes)))
+	}
+	parts = append(parts, "elapsed "+time.Since(c.Start).Round(time.Second).String())
+	if eta := p.eta(); eta > 0 {
+		parts = append(parts, "ETA "+eta.Round(time.Second).String())
+	}
+	if c.lastTask != "" {
+		parts = append(parts, "last: "+c.lastTask)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (p *Progress) entry() *log.Entry {
+	c := p.cur
+	entry := log.WithField("op", p.op).
+		WithField("phase", c.Name).
+		WithField("phase_index", c.index).
+		WithField("phases", len(p.phases)).
+		WithField("elapsed_ms", time.Since(c.Start).Milliseconds()).
+		WithField("total_elapsed_ms", time.Since(p.start).Milliseconds())
+	if c.Tasks > 0 {
+		entry = entry.WithField("tasks", c.Tasks).WithField("done_tasks", c.DoneTasks)
+	}
+	if c.Bytes > 0 {
+		entry = entry.WithField("bytes", c.Bytes).WithField("done_bytes", c.DoneBytes)
+	}
+	if eta := p.eta(); eta > 0 {
+		entry = entry.WithField("eta_ms", eta.Milliseconds())
+	}
+	return entry
+}
+
+// Records returns the records of all the phases started
+func (p *Progress) Records() []*Record {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.records
+}
@@ -0,0 +1,79 @@
+package progress
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapitalize(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("Backup", capitalize("backup"))
+	assert.Equal("Restore", capitalize("Restore"))
+	assert.Equal("", capitalize(""))
+}
+
+func TestProgressTerminal(t *testing.T) {
+	assert := assert.New(t)
+
+	out := &bytes.Buffer{}
+	p := newProgress("backup", []string{"upload meta", "upload storage"}, out, true)
+	p.Phase("upload meta")
+	assert.Contains(out.String(), "\r\033[KBackup [1/2] upload meta")
+
+	p.Phase("upload storage")
+	assert.Contains(out.String(), "Backup [1/2] upload meta done in")
+	p.Total(2, 2048)
+	p.Done("data0", 1024)
+	out.Reset()
+	p.mu.Lock()
+	p.report()
+	p.mu.Unlock()
+	line := out.String()
+	assert.True(strings.HasPrefix(line, "\r\033[KBackup [2/2] upload storage, 1/2 tasks, 1KB/2KB, elapsed"), line)
+	assert.Contains(line, "last: data0")
+
+	p.Finish(errors.New("agent is down"))
+	assert.Contains(out.String(), "Backup [2/2] upload storage, 1/2 tasks")
+	assert.True(strings.HasSuffix(out.String(), " failed\n"))
+
+	records := p.Records()
+	assert.Len(records, 2)
+	assert.Equal("upload meta", records[0].Name)
+	assert.Empty(records[0].Error)
+	assert.Equal("upload storage", records[1].Name)
+	assert.Equal(1, records[1].DoneTasks)
+	assert.Equal(int64(1024), records[1].DoneBytes)
+	assert.Equal("agent is down", records[1].Error)
+}
+
+func TestProgressEvents(t *testing.T) {
+	assert := assert.New(t)
+
+	logs := &bytes.Buffer{}
+	log.SetOutput(logs)
+	defer log.SetOutput(os.Stderr)
+	out := &bytes.Buffer{}
+	p := newProgress("restore", []string{"download data"}, out, false)
+	p.Phase("download data")
+	p.Total(1, 0)
+	p.Done("storaged[192.168.0.1:9779]:data0", 0)
+	p.Finish(nil)
+
+	assert.Empty(out.String())
+	assert.Contains(logs.String(), "Progress task finished.")
+	assert.Contains(logs.String(), "storaged[192.168.0.1:9779]:data0")
+	assert.Contains(logs.String(), "Progress phase finished.")
+	assert.NotContains(logs.String(), "bytes=")
+
+	records := p.Records()
+	assert.Len(records, 1)
+	assert.Equal(1, records[0].DoneTasks)
+	assert.False(records[0].End.IsZero())
+}
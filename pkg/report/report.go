@@ -0,0 +1,102 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/vesoft-inc/nebula-br/pkg/progress"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+)
+
+// Error is an error occurred in the command, host and path are
+// recorded if the error comes from a task on a host
+type Error struct {
+	Host    string `json:"host,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+// Report is the result of a command, which is written to a json file for automation
+type Report struct {
+	Command    string    `json:"command"`
+	BackupName string    `json:"backup_name,omitempty"`
+	Success    bool      `json:"success"`
+	StartTime  time.Time `json:"start_time"`
+	EndTime    time.Time `json:"end_time"`
+
+	Hosts  []string           `json:"hosts"`
+	Phases []*progress.Record `json:"phases"`
+
+	// Files and Bytes are the amount transferred, null if unknown, and SizeUnknown tells why
+	Files        *int   `json:"files"`
+	Bytes        *int64 `json:"bytes"`
+	SizeUnknown  string `json:"size_unknown,omitempty"`
+	PaceRate     string `json:"pace_rate,omitempty"`
+	HostPaceRate string `json:"host_pace_rate,omitempty"`
+
+	Errors []*Error `json:"errors"`
+
+	// cleanup runs when backup failed, and fix runs when restore failed
+	CleanupRan   bool   `json:"cleanup_ran"`
+	CleanupError string `json:"cleanup_error,omitempty"`
+	FixRan       bool   `json:"fix_ran"`
+	FixError     string `json:"fix_error,omitempty"`
+}
+
+func New(command string) *Report {
+	return &Report{
+		Command:   command,
+		StartTime: time.Now(),
+	}
+}
+
+// SetSize records the files and bytes transferred
+func (r *Report) SetSize(files int, bytes int64) {
+	r.Files, r.Bytes = &files, &bytes
+}
+
+// SetBytes records the bytes transferred, when the files are unknown
+func (r *Report) SetBytes(bytes int64) {
+	r.Bytes = &bytes
+}
+
+// AddError records the error, the errors of concurrent tasks are recorded one by one
+func (r *Report) AddError(err error) {
+	if err == nil {
+		return
+	}
+
+	var multi utils.MultiError
+	if errors.As(err, &multi) {
+		for _, e := range multi {
+			r.AddError(e)
+		}
+		return
+	}
+
+	e := &Error{Message: err.Error()}
+	var taskErr *utils.TaskError
+	if errors.As(err, &taskErr) {
+		e.Host, e.Path = taskErr.Host, taskErr.Path
+	}
+	r.Errors = append(r.Errors, e)
+}
+
+// Write finishes the report with the result of command, and writes it to filename
+func (r *Report) Write(filename string, err error) error {
+	r.EndTime = time.Now()
+	r.Success = err == nil
+	r.AddError(err)
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal report failed: %w", err)
+	}
+	if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("write report to %s failed: %w", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vesoft-inc/nebula-br/pkg/progress"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+)
+
+func tempReport(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "br_report")
+	assert.Nil(t, err)
+	return filepath.Join(dir, "report.json"), func() { os.RemoveAll(dir) }
+}
+
+func TestReportRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	start := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	rep := New("br backup full")
+	rep.BackupName = "BACKUP_2022_03_01_10_00_00"
+	rep.Hosts = []string{"192.168.0.1", "192.168.0.2"}
+	rep.Phases = []*progress.Record{
+		{Name: "upload storage", Start: start, End: start.Add(time.Minute), Tasks: 2, DoneTasks: 1, Bytes: 2048, DoneBytes: 1024},
+	}
+	rep.SetSize(3, 4096)
+	rep.PaceRate = "100MB/s"
+	rep.CleanupRan = true
+
+	filename, clean := tempReport(t)
+	defer clean()
+	err := errors.New("upload failed")
+	multi := utils.MultiError{&utils.TaskError{Host: "192.168.0.1:9779", Path: "/data/nebula", Err: err}, err}
+	assert.Nil(rep.Write(filename, multi))
+
+	data, err := ioutil.ReadFile(filename)
+	assert.Nil(err)
+	parsed := &Report{}
+	assert.Nil(json.Unmarshal(data, parsed))
+
+	assert.False(parsed.Success)
+	assert.Equal(rep.Command, parsed.Command)
+	assert.Equal(rep.BackupName, parsed.BackupName)
+	assert.True(rep.StartTime.Equal(parsed.StartTime))
+	assert.True(rep.EndTime.Equal(parsed.EndTime))
+	assert.Equal(rep.Hosts, parsed.Hosts)
+	assert.Len(parsed.Phases, 1)
+	assert.Equal(*rep.Phases[0], *parsed.Phases[0])
+	assert.Equal(3, *parsed.Files)
+	assert.Equal(int64(4096), *parsed.Bytes)
+	assert.Empty(parsed.SizeUnknown)
+	assert.Equal("100MB/s", parsed.PaceRate)
+	assert.True(parsed.CleanupRan)
+	assert.Equal([]*Error{
+		{Host: "192.168.0.1:9779", Path: "/data/nebula", Message: "upload failed"},
+		{Message: "upload failed"},
+	}, parsed.Errors)
+}
+
+func TestReportSizeUnknown(t *testing.T) {
+	assert := assert.New(t)
+
+	rep := New("br restore full")
+	rep.SizeUnknown = "unknown, no manifest"
+	filename, clean := tempReport(t)
+	defer clean()
+	assert.Nil(rep.Write(filename, nil))
+
+	data, err := ioutil.ReadFile(filename)
+	assert.Nil(err)
+	fields := make(map[string]interface{})
+	assert.Nil(json.Unmarshal(data, &fields))
+	assert.Contains(fields, "files")
+	assert.Nil(fields["files"])
+	assert.Contains(fields, "bytes")
+	assert.Nil(fields["bytes"])
+	assert.Equal("unknown, no manifest", fields["size_unknown"])
+	assert.Equal(true, fields["success"])
+}
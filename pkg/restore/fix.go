@@ -3,7 +3,6 @@ package restore
 import (
 	"fmt"
 	"path/filepath"
-	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -35,80 +34,114 @@ func NewFixFrom(r *Restore) (*Fix, error) {
 	}, nil
 }
 
-// Move back the data dir in restore process
-func (f *Fix) fixData() error {
+// dirBack is an origin dir to move back from bpath to opath, the dir in opath is removed first if remove
+type dirBack struct {
+	name   string
+	agent  *clients.NebulaAgent
+	opath  string
+	bpath  string
+	remove bool
+}
+
+func (d *dirBack) String() string {
+	if d.remove {
+		return fmt.Sprintf("%s: remove %s, then move %s back to it", d.name, d.opath, d.bpath)
+	}
+	return fmt.Sprintf("%s: move %s back to %s", d.name, d.bpath, d.opath)
+}
+
+// planData returns the origin dirs existing in cluster to move back, without changing anything
+func (f *Fix) planData() ([]*dirBack, error) {
+	// no data dir has been moved, and the current data should not be removed
+	if f.backSuffix == "" {
+		return nil, nil
+	}
+
 	services := f.hosts.GetStorages()
 	services = append(services, f.hosts.GetMetas()...)
 
+	plan := make([]*dirBack, 0)
 	for _, s := range services {
-		name := fmt.Sprintf("%s[%s]", s.GetRole().String(), utils.StringifyAddr(s.GetAddr()))
-		logger := log.WithField("name", name)
-
+		name := serviceName(s)
 		agent, err := f.agentMgr.GetAgentFor(s.GetAddr())
 		if err != nil {
-			return fmt.Errorf("get agent for %s failed: %w", name, err)
+			return nil, fmt.Errorf("get agent for %s failed: %w", name, err)
 		}
 
 		if s.GetRole() == meta.HostRole_META && len(s.Dir.Data) != 1 {
-			return fmt.Errorf("meta service: %s should only have one data dir, but %d",
+			return nil, fmt.Errorf("meta service: %s should only have one data dir, but %d",
 				name, len(s.Dir.Data))
 		}
 
+		// only the space dirs of storaged are moved if some spaces are restored
+		var paths []string
 		for _, d := range s.Dir.Data {
-			opath := filepath.Join(string(d), "nebula")
-			bpath := fmt.Sprintf("%s%s", opath, f.backSuffix)
-
-			// check if the old data exist
-			existReq := &pb.ExistDirRequest{
-				Path: bpath,
+			if s.GetRole() == meta.HostRole_STORAGE {
+				paths = append(paths, f.r.dataPaths(d)...)
+			} else {
+				paths = append(paths, filepath.Join(string(d), "nebula"))
 			}
-			res, err := agent.ExistDir(existReq)
+		}
+		dirs := make([]*dirBack, 0, len(paths)+1)
+		for _, opath := range paths {
+			dirs = append(dirs, &dirBack{name: name, agent: agent, opath: opath, bpath: opath + f.backSuffix, remove: true})
+		}
+		// the old cluster.id is moved back as is
+		if s.GetRole() == meta.HostRole_STORAGE && len(f.r.spaces) == 0 {
+			opath := filepath.Join(string(s.GetDir().GetRoot()), "cluster.id")
+			dirs = append(dirs, &dirBack{name: name, agent: agent, opath: opath, bpath: opath + f.backSuffix})
+		}
+
+		for _, d := range dirs {
+			res, err := agent.ExistDir(&pb.ExistDirRequest{Path: d.bpath})
 			if err != nil {
-				return fmt.Errorf("check %s:%s exist failed: %w", name, opath, err)
+				return nil, fmt.Errorf("check %s:%s exist failed: %w", name, d.bpath, err)
 			}
 			if !res.Exist {
-				logger.WithField("path", bpath).Debug("Origin backup storage data path does not exist, skip it")
+				log.WithField("name", name).WithField("path", d.bpath).
+					Debug("Origin backup data path does not exist, skip it")
 				continue
 			}
+			plan = append(plan, d)
+		}
+	}
+	return plan, nil
+}
 
-			// remove the newly downloaded data
-			rmReq := &pb.RemoveDirRequest{
-				Path: opath,
-			}
-			_, err = agent.RemoveDir(rmReq)
-			if err != nil {
-				return fmt.Errorf("remove new origin dir %s failed: %w", opath, err)
-			}
+// Move back the data dir in restore process
+func (f *Fix) fixData() error {
+	plan, err := f.planData()
+	if err != nil {
+		return err
+	}
+	if len(plan) == 0 {
+		log.Info("Origin data paths have not been moved, no need to fix data.")
+		return nil
+	}
 
-			// move the old data back
-			req := &pb.MoveDirRequest{
-				SrcPath: bpath,
-				DstPath: opath,
-			}
-			_, err = agent.MoveDir(req)
+	for _, d := range plan {
+		// remove the newly downloaded data
+		if d.remove {
+			_, err = d.agent.RemoveDir(&pb.RemoveDirRequest{Path: d.opath})
 			if err != nil {
-				return fmt.Errorf("move data dir back from %s to %s failed: %w", opath, bpath, err)
+				return fmt.Errorf("remove new origin dir %s:%s failed: %w", d.name, d.opath, err)
 			}
-
-			logger.WithField("origin path", opath).
-				WithField("backup path", bpath).
-				Infof("Moveback origin %s data path successfully", s.GetRole().String())
 		}
 
-		// move the old cluster.id back
-		if s.GetRole() == meta.HostRole_STORAGE {
-			opath := filepath.Join(string(s.GetDir().GetRoot()), "cluster.id")
-			bpath := fmt.Sprintf("%s%s", opath, f.backSuffix)
-
-			req := &pb.MoveDirRequest{
-				SrcPath: bpath,
-				DstPath: opath,
-			}
-			_, err = agent.MoveDir(req)
-			if err != nil && !utils.IsNotExist(err) {
-				return fmt.Errorf("move cluster.id back from %s to %s failed: %w", bpath, opath, err)
-			}
+		// move the old data back
+		req := &pb.MoveDirRequest{
+			SrcPath: d.bpath,
+			DstPath: d.opath,
+		}
+		_, err = d.agent.MoveDir(req)
+		if err != nil {
+			return fmt.Errorf("move data dir back from %s to %s failed: %w", d.bpath, d.opath, err)
 		}
+
+		log.WithField("name", d.name).
+			WithField("origin path", d.opath).
+			WithField("backup path", d.bpath).
+			Info("Moveback origin data path successfully")
 	}
 
 	return nil
@@ -186,23 +219,12 @@ func (f *Fix) startDead(deadServices []*meta.ServiceInfo) error {
 	return nil
 }
 
-func retry(action func() error, aname string, times int) (err error) {
-	for try := 1; try <= times; try++ {
-		err = action()
-		if err == nil {
-			return
-		}
-
-		log.WithError(err).Infof("%s failed, try times=%d.", aname, try)
-		time.Sleep(time.Second * time.Duration(try))
-	}
-
-	return
+// retry retries the action by the retry policy of agents, since it is mostly agent calls
+func (f *Fix) retry(action func() error, aname string) error {
+	return f.agentMgr.RetryPolicy().Do(f.r.ctx, aname, action)
 }
 
 func (f *Fix) Fix() error {
-	tryTimes := 3
-
 	// check if all services alive
 	allAlive := false
 	checkAlive := func() error {
@@ -213,7 +235,7 @@ func (f *Fix) Fix() error {
 			return nil
 		}
 	}
-	err := retry(checkAlive, "Get dead services", tryTimes)
+	err := f.retry(checkAlive, "Get dead services")
 	if allAlive {
 		log.Info("All services are OK.")
 		return nil
@@ -222,14 +244,45 @@ func (f *Fix) Fix() error {
 		return err
 	}
 
-	// stop all service for data movement
-	if err := retry(f.r.stopCluster, "Stop all services", tryTimes); err != nil {
-		return err
+	return f.moveBack()
+}
+
+// Rollback moves back the origin data recorded in restore journal and restarts the cluster,
+// it is done whether the services are alive or not, since a finished phase may be rolled back.
+func (f *Fix) Rollback() error {
+	j := f.r.journal
+	if j == nil {
+		return fmt.Errorf("no restore journal to rollback")
 	}
 
-	// move back data path
-	if err := retry(f.fixData, "Fix data", tryTimes); err != nil {
-		return err
+	switch j.Phase {
+	case PhaseFinished:
+		return fmt.Errorf("restore of %s has finished and the origin data has been cleaned up", j.BackupName)
+	case PhaseRolledBack:
+		log.WithField("backup", j.BackupName).Info("Restore has been rolled back.")
+		return nil
+	}
+	if j.Phase >= PhaseSpacesDropped && !j.AllSpaces {
+		log.WithField("backup", j.BackupName).
+			Warn("Spaces dropped from meta service could not be rolled back, their storage data will be kept.")
+	}
+
+	return f.moveBack()
+}
+
+// moveBack stops all services, moves back the origin data and then starts the cluster,
+// only the dead services are started if the origin data has not been moved.
+func (f *Fix) moveBack() error {
+	if f.backSuffix != "" {
+		// stop all service for data movement
+		if err := f.retry(f.r.stopCluster, "Stop all services"); err != nil {
+			return err
+		}
+
+		// move back data path
+		if err := f.retry(f.fixData, "Fix data"); err != nil {
+			return err
+		}
 	}
 
 	// start all services
@@ -244,9 +297,15 @@ func (f *Fix) Fix() error {
 		}
 		return nil
 	}
-	if err := retry(getdeadThenStart, "Get dead services then start", tryTimes); err != nil {
+	if err := f.retry(getdeadThenStart, "Get dead services then start"); err != nil {
 		return err
 	}
 
+	// the origin data is back, so the restore journal should not be resumed any more
+	if f.r.journal != nil {
+		if err := f.r.setPhase(PhaseRolledBack); err != nil {
+			log.WithError(err).Warn("Save restore journal failed.")
+		}
+	}
 	return nil
 }
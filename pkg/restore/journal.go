@@ -0,0 +1,303 @@
+package restore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vesoft-inc/nebula-br/pkg/config"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+	"github.com/vesoft-inc/nebula-go/v3/nebula"
+	"github.com/vesoft-inc/nebula-go/v3/nebula/meta"
+)
+
+// Phase is the last finished step of a restore, phases are in execution order
+type Phase int
+
+const (
+	PhasePrepared Phase = iota
+	PhaseSpacesDropped
+	PhaseClusterStopped
+	PhaseOriginalMoved
+	PhaseDataDownloaded
+	PhaseMetaStarted
+	PhaseMetaRestored
+	PhaseServicesStarted
+	PhaseValidated
+	PhaseFinished
+	PhaseRolledBack
+)
+
+var phaseNames = []string{
+	"prepared",
+	"spaces_dropped",
+	"cluster_stopped",
+	"original_moved",
+	"data_downloaded",
+	"meta_started",
+	"meta_restored",
+	"services_started",
+	"validated",
+	"finished",
+	"rolled_back",
+}
+
+func (p Phase) String() string {
+	if p < 0 || int(p) >= len(phaseNames) {
+		return fmt.Sprintf("unknown(%d)", int(p))
+	}
+	return phaseNames[p]
+}
+
+func (p Phase) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *Phase) UnmarshalText(text []byte) error {
+	for i, name := range phaseNames {
+		if name == string(text) {
+			*p = Phase(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown restore phase %s", string(text))
+}
+
+type journalService struct {
+	Role string   `json:"role"`
+	Addr string   `json:"addr"`
+	Root string   `json:"root"`
+	Data []string `json:"data"`
+}
+
+type journalPair struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Paths []int  `json:"paths"`
+}
+
+// Journal records the progress of a restore, so that an interrupted restore
+// could be resumed or rolled back even if the meta service has been stopped.
+type Journal struct {
+	BackupName string                `json:"backup_name"`
+	Storage    string                `json:"storage"` // root uri of backups, without credentials
+	Phase      Phase                 `json:"phase"`
+	BackSuffix string                `json:"back_suffix"` // suffix of the moved origin data dirs
+	AllSpaces  bool                  `json:"all_spaces"`
+	Spaces     []nebula.GraphSpaceID `json:"spaces,omitempty"` // restored by space dirs, empty if the whole data dirs
+	Services   []*journalService     `json:"services"`         // cluster topology before restore
+	Pairs      []*journalPair        `json:"pairs"`            // storaged in backup -> storaged in cluster
+	Downloads  map[string]bool       `json:"downloads"`
+	UpdateTime time.Time             `json:"update_time"`
+}
+
+func newJournal(backupName string, allSpaces bool, spaces []nebula.GraphSpaceID, hosts *utils.NebulaHosts,
+	pairs []*config.StoragePair) *Journal {
+	j := &Journal{
+		BackupName: backupName,
+		Phase:      PhasePrepared,
+		AllSpaces:  allSpaces,
+		Spaces:     spaces,
+		Downloads:  make(map[string]bool),
+	}
+
+	for _, services := range hosts.GetHostServices() {
+		for _, s := range services {
+			js := &journalService{
+				Role: s.GetRole().String(),
+				Addr: utils.StringifyAddr(s.GetAddr()),
+				Root: string(s.GetDir().GetRoot()),
+			}
+			for _, d := range s.GetDir().GetData() {
+				js.Data = append(js.Data, string(d))
+			}
+			j.Services = append(j.Services, js)
+		}
+	}
+
+	for _, p := range pairs {
+		j.Pairs = append(j.Pairs, &journalPair{
+			From:  utils.StringifyAddr(p.From),
+			To:    utils.StringifyAddr(p.To.GetAddr()),
+			Paths: p.Paths,
+		})
+	}
+	return j
+}
+
+// hosts rebuilds the cluster topology recorded in journal
+func (j *Journal) hosts() (*utils.NebulaHosts, error) {
+	resp := &meta.ListClusterInfoResp{
+		Code:         nebula.ErrorCode_SUCCEEDED,
+		HostServices: make(map[string][]*meta.ServiceInfo),
+	}
+	for _, js := range j.Services {
+		role, err := meta.HostRoleFromString(js.Role)
+		if err != nil {
+			return nil, fmt.Errorf("parse role of %s failed: %w", js.Addr, err)
+		}
+		addr, err := utils.ParseAddr(js.Addr)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s failed: %w", js.Addr, err)
+		}
+		dir := &nebula.DirInfo{Root: []byte(js.Root)}
+		for _, d := range js.Data {
+			dir.Data = append(dir.Data, []byte(d))
+		}
+
+		resp.HostServices[addr.Host] = append(resp.HostServices[addr.Host],
+			&meta.ServiceInfo{Dir: dir, Addr: addr, Role: role})
+	}
+
+	hosts := &utils.NebulaHosts{}
+	if err := hosts.LoadFrom(resp); err != nil {
+		return nil, err
+	}
+	return hosts, nil
+}
+
+// storagePairs rebuilds the storaged pairs recorded in journal
+func (j *Journal) storagePairs(hosts *utils.NebulaHosts) ([]*config.StoragePair, error) {
+	storages := make(map[string]*meta.ServiceInfo)
+	for _, s := range hosts.GetStorages() {
+		storages[utils.StringifyAddr(s.GetAddr())] = s
+	}
+
+	pairs := make([]*config.StoragePair, 0, len(j.Pairs))
+	for _, jp := range j.Pairs {
+		from, err := utils.ParseAddr(jp.From)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s failed: %w", jp.From, err)
+		}
+		to, ok := storages[jp.To]
+		if !ok {
+			return nil, fmt.Errorf("storaged %s is not in the journal topology", jp.To)
+		}
+		pairs = append(pairs, &config.StoragePair{From: from, To: to, Paths: jp.Paths})
+	}
+	return pairs, nil
+}
+
+func DumpJournalToFile(j *Journal, filename string) error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal restore journal failed: %w", err)
+	}
+
+	// write to a temporary file first, a crash should never leave a broken journal
+	tmpFile := filename + ".tmp"
+	err = ioutil.WriteFile(tmpFile, data, 0644)
+	if err != nil {
+		return fmt.Errorf("write restore journal to %s failed: %w", tmpFile, err)
+	}
+	if err = os.Rename(tmpFile, filename); err != nil {
+		return fmt.Errorf("rename %s to %s failed: %w", tmpFile, filename, err)
+	}
+	return nil
+}
+
+func ParseJournalFromFile(filename string) (*Journal, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read file %s failed: %w", filename, err)
+	}
+
+	j := &Journal{}
+	err = json.Unmarshal(data, j)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal restore journal from %s failed: %w", filename, err)
+	}
+	if j.Downloads == nil {
+		j.Downloads = make(map[string]bool)
+	}
+	return j, nil
+}
+
+// loadJournal loads the journal from local file, and from external storage
+// if the local one does not exist, e.g. the machine running br is changed.
+func (r *Restore) loadJournal() (*Journal, error) {
+	if _, err := os.Stat(r.cfg.JournalPath); err == nil {
+		return ParseJournalFromFile(r.cfg.JournalPath)
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("stat %s failed: %w", r.cfg.JournalPath, err)
+	}
+
+	if r.backupName == "" {
+		return nil, fmt.Errorf("local journal %s does not exist", r.cfg.JournalPath)
+	}
+	if err := utils.EnsureDir(utils.LocalTmpDir); err != nil {
+		return nil, err
+	}
+	journalUri, _ := utils.UriJoin(r.rootUri, r.backupName, utils.RestoreJournalName)
+	tmpLocalPath := filepath.Join(utils.LocalTmpDir, utils.RestoreJournalName)
+	err := r.sto.Download(r.ctx, tmpLocalPath, journalUri, false)
+	if err != nil {
+		return nil, fmt.Errorf("local journal %s does not exist, and download %s failed: %w",
+			r.cfg.JournalPath, journalUri, err)
+	}
+	defer os.Remove(tmpLocalPath)
+	log.WithField("uri", journalUri).Info("Load restore journal from external storage.")
+
+	return ParseJournalFromFile(tmpLocalPath)
+}
+
+// StorageOfJournal returns the root uri of backups recorded in the local journal
+func StorageOfJournal(filename string) (string, error) {
+	j, err := ParseJournalFromFile(filename)
+	if err != nil {
+		return "", err
+	}
+	if j.Storage == "" {
+		return "", fmt.Errorf("no storage is recorded in journal %s, please specify it by --storage", filename)
+	}
+	return j.Storage, nil
+}
+
+// saveJournal persists the journal to local file, and to the backup dir in external storage if upload,
+// which is only done when the phase changes, since the downloads are recorded by every download worker.
+// Failure of the upload is only warned since restore could go on with the local one.
+func (r *Restore) saveJournal(upload bool) error {
+	r.journalMu.Lock()
+	defer r.journalMu.Unlock()
+
+	r.journal.UpdateTime = time.Now()
+	if err := DumpJournalToFile(r.journal, r.cfg.JournalPath); err != nil {
+		return err
+	}
+	if !upload {
+		return nil
+	}
+
+	journalUri, _ := utils.UriJoin(r.rootUri, r.backupName, utils.RestoreJournalName)
+	if err := r.sto.Upload(r.ctx, journalUri, r.cfg.JournalPath, false); err != nil {
+		log.WithError(err).WithField("uri", journalUri).Warn("Upload restore journal failed.")
+	}
+	return nil
+}
+
+func (r *Restore) setPhase(p Phase) error {
+	r.journalMu.Lock()
+	r.journal.Phase = p
+	r.journalMu.Unlock()
+
+	log.WithField("backup", r.backupName).WithField("phase", p).Info("Restore phase finished.")
+	return r.saveJournal(true)
+}
+
+func (r *Restore) downloaded(task string) bool {
+	r.journalMu.Lock()
+	defer r.journalMu.Unlock()
+	return r.journal.Downloads[task]
+}
+
+func (r *Restore) markDownloaded(task string) error {
+	r.journalMu.Lock()
+	r.journal.Downloads[task] = true
+	r.journalMu.Unlock()
+	return r.saveJournal(false)
+}
@@ -0,0 +1,208 @@
+package restore
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+	"github.com/vesoft-inc/nebula-go/v3/nebula/meta"
+)
+
+const dryRunSuffix = "_old_<timestamp>"
+
+type planSection struct {
+	title  string
+	header []string
+	rows   [][]string
+}
+
+// plan describes the steps the restore will go through in order
+type plan []*planSection
+
+func (p plan) print(w io.Writer) {
+	for _, s := range p {
+		fmt.Fprintf(w, "\n%s:\n", s.title)
+		if len(s.rows) == 0 {
+			fmt.Fprintln(w, "  (none)")
+			continue
+		}
+
+		tw := tablewriter.NewWriter(w)
+		tw.SetHeader(s.header)
+		tw.AppendBulk(s.rows)
+		tw.Render()
+	}
+}
+
+func serviceName(s *meta.ServiceInfo) string {
+	return fmt.Sprintf("%s[%s]", strings.ToLower(s.GetRole().String()), utils.StringifyAddr(s.GetAddr()))
+}
+
+func (r *Restore) plan(bakMeta *meta.BackupMeta, existedSpaces []string) plan {
+	var p plan
+
+	mapping := &planSection{
+		title:  "Storage host mapping",
+		header: []string{"backup storaged", "target storaged", "data paths"},
+	}
+	for _, pair := range r.storagePairs {
+		paths := make([]string, 0, len(pair.Paths))
+		for i, idx := range pair.Paths {
+			paths = append(paths, fmt.Sprintf("data%d -> %s", i, string(pair.To.GetDir().GetData()[idx])))
+		}
+		mapping.rows = append(mapping.rows, []string{
+			utils.StringifyAddr(pair.From), utils.StringifyAddr(pair.To.GetAddr()), strings.Join(paths, "\n"),
+		})
+	}
+	p = append(p, mapping)
+
+	if !bakMeta.GetAllSpaces() {
+		drop := &planSection{
+			title:  "Spaces to drop",
+			header: []string{"space"},
+		}
+		sort.Strings(existedSpaces)
+		for _, s := range existedSpaces {
+			drop.rows = append(drop.rows, []string{s})
+		}
+		p = append(p, drop)
+	}
+
+	stop := &planSection{
+		title:  "Services to stop",
+		header: []string{"service", "root dir"},
+	}
+	for _, services := range r.hosts.GetHostServices() {
+		for _, s := range services {
+			if s.GetRole() == meta.HostRole_AGENT {
+				continue
+			}
+			stop.rows = append(stop.rows, []string{serviceName(s), string(s.GetDir().GetRoot())})
+		}
+	}
+	p = append(p, stop)
+
+	move := &planSection{
+		title:  "Directories to move",
+		header: []string{"service", "from", "to"},
+	}
+	for _, s := range r.hosts.GetStorages() {
+		for _, d := range s.GetDir().GetData() {
+			for _, opath := range r.dataPaths(d) {
+				move.rows = append(move.rows, []string{serviceName(s), opath, opath + dryRunSuffix})
+			}
+		}
+		if len(r.spaces) != 0 {
+			continue
+		}
+		opath := filepath.Join(string(s.GetDir().GetRoot()), "cluster.id")
+		move.rows = append(move.rows, []string{serviceName(s), opath, opath + dryRunSuffix})
+	}
+	if bakMeta.GetAllSpaces() {
+		for _, m := range r.hosts.GetMetas() {
+			for _, d := range m.GetDir().GetData() {
+				opath := fmt.Sprintf("%s/nebula", string(d))
+				move.rows = append(move.rows, []string{serviceName(m), opath, opath + dryRunSuffix})
+			}
+		}
+	}
+	p = append(p, move)
+
+	download := &planSection{
+		title:  "Files to download",
+		header: []string{"service", "from", "to", "inherited files"},
+	}
+	metaUri, _ := utils.UriJoin(r.rootUri, r.backupName, "meta")
+	for _, m := range r.hosts.GetMetas() {
+		if len(m.GetDir().GetData()) == 0 {
+			continue
+		}
+		download.rows = append(download.rows, []string{serviceName(m), metaUri, string(m.GetDir().GetData()[0]), "0"})
+	}
+	storageUri, _ := utils.UriJoin(r.rootUri, r.backupName, "data")
+	for _, pair := range r.storagePairs {
+		for i, idx := range pair.Paths {
+			for _, dir := range r.downloadDirs(bakMeta, pair, i) {
+				externalUri, _ := utils.UriJoin(storageUri, dir.prefix)
+				localDir := filepath.Join(string(pair.To.GetDir().GetData()[idx]), dir.local)
+				inherited := 0
+				if r.refs != nil {
+					for d, files := range r.refs.Files {
+						if strings.HasPrefix(d, dir.prefix+"/") {
+							inherited += len(files)
+						}
+					}
+				}
+				download.rows = append(download.rows, []string{
+					serviceName(pair.To), externalUri, localDir, fmt.Sprintf("%d", inherited),
+				})
+			}
+		}
+	}
+	p = append(p, download)
+
+	restoreMeta := &planSection{
+		title:  "Meta to restore",
+		header: []string{"service", "meta files", "host pairs"},
+	}
+	pairs := make([]string, 0, len(r.storagePairs))
+	for _, pair := range r.storagePairs {
+		pairs = append(pairs, fmt.Sprintf("%s -> %s", utils.StringifyAddr(pair.From), utils.StringifyAddr(pair.To.GetAddr())))
+	}
+	for _, m := range r.hosts.GetMetas() {
+		restoreMeta.rows = append(restoreMeta.rows, []string{
+			serviceName(m), fmt.Sprintf("%d", len(bakMeta.GetMetaFiles())), strings.Join(pairs, "\n"),
+		})
+	}
+	p = append(p, restoreMeta)
+
+	start := &planSection{
+		title:  "Services to start",
+		header: []string{"service", "root dir"},
+	}
+	services := append(r.hosts.GetMetas(), r.hosts.GetStorages()...)
+	services = append(services, r.hosts.GetGraphs()...)
+	for _, s := range services {
+		start.rows = append(start.rows, []string{serviceName(s), string(s.GetDir().GetRoot())})
+	}
+	p = append(p, start)
+
+	return p
+}
+
+// DryRun checks whether the backup could be restored to this cluster, and prints
+// the steps the restore will go through, nothing in the cluster will be changed.
+func (r *Restore) DryRun() error {
+	if err := utils.EnsureDir(utils.LocalTmpDir); err != nil {
+		return err
+	}
+	defer func() {
+		if err := utils.RemoveDir(utils.LocalTmpDir); err != nil {
+			log.WithError(err).Errorf("Remove tmp dir %s failed.", utils.LocalTmpDir)
+		}
+	}()
+
+	bakMeta, err := r.prepare()
+	if err != nil {
+		return err
+	}
+
+	var existed []string
+	if !bakMeta.GetAllSpaces() {
+		existed, err = r.checkSpaces(bakMeta.GetSpaceBackups())
+		if err != nil {
+			return fmt.Errorf("check spaces failed: %w", err)
+		}
+	}
+
+	fmt.Printf("Restore plan of backup %s:\n", r.backupName)
+	r.plan(bakMeta, existed).print(os.Stdout)
+	return nil
+}
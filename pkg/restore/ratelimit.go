@@ -0,0 +1,106 @@
+package restore
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+)
+
+// the agent downloads a whole dir in one request, and could not be throttled,
+// so the starts of transfers are paced by their sizes recorded in manifests,
+// every transfer still runs at full speed once started.
+type rateLimit struct {
+	global *utils.RateLimiter
+	hosts  map[string]*utils.RateLimiter // host -> limiter, shared by the data paths in it
+}
+
+func (r *Restore) limited() bool {
+	return r.cfg.PaceRate > 0 || r.cfg.HostPaceRate > 0
+}
+
+// loadSizes loads the file sizes from the manifests of the backup and the backups
+// in its base chain, which are used to report progress and necessary to pace the transfers.
+func (r *Restore) loadSizes() error {
+	names := map[string]bool{r.backupName: true}
+	if r.refs != nil {
+		for _, files := range r.refs.Files {
+			for _, owner := range files {
+				names[owner] = true
+			}
+		}
+	}
+
+	sizes := make(map[string]int64)
+	for name := range names {
+		m, err := utils.DownloadManifest(r.ctx, r.sto, r.rootUri, name)
+		if err != nil {
+			return fmt.Errorf("get manifest of %s failed: %w", name, err)
+		}
+		for _, f := range m.Files {
+			sizes[path.Join(name, f.Path)] = f.Size
+		}
+	}
+	r.sizes = sizes
+	return nil
+}
+
+func (r *Restore) loadRateLimit() error {
+	if err := r.loadSizes(); err != nil {
+		if r.limited() {
+			return fmt.Errorf("pacing downloads needs the file sizes in manifests: %w", err)
+		}
+		log.WithError(err).Info("Load file sizes failed, the bytes to download are unknown.")
+	}
+	if !r.limited() {
+		return nil
+	}
+
+	l := &rateLimit{
+		global: utils.NewRateLimiter(r.cfg.PaceRate),
+		hosts:  make(map[string]*utils.RateLimiter),
+	}
+	for _, pair := range r.storagePairs {
+		l.hosts[pair.To.GetAddr().GetHost()] = utils.NewRateLimiter(r.cfg.HostPaceRate)
+	}
+	r.rateLimit = l
+
+	log.WithField("pace rate", utils.FormatRate(r.cfg.PaceRate)).
+		WithField("host pace rate", utils.FormatRate(r.cfg.HostPaceRate)).
+		Info("Pace the downloads of storage dirs by their sizes.")
+	return nil
+}
+
+// sizeOf returns the total size of files under p, which is relative to backup root,
+// it returns 0 if the sizes are unknown
+func (r *Restore) sizeOf(p string) int64 {
+	if size, ok := r.sizes[p]; ok {
+		return size
+	}
+
+	var size int64
+	for f, s := range r.sizes {
+		if f == p || strings.HasPrefix(f, p+"/") {
+			size += s
+		}
+	}
+	return size
+}
+
+// wait blocks until the files under p could be downloaded to the host
+func (r *Restore) wait(ctx context.Context, host, p string) error {
+	l := r.rateLimit
+	if l == nil {
+		return nil
+	}
+
+	size := r.sizeOf(p)
+	if err := l.global.Wait(ctx, size); err != nil {
+		return err
+	}
+	return l.hosts[host].Wait(ctx, size)
+}
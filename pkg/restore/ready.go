@@ -0,0 +1,190 @@
+package restore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	pb "github.com/vesoft-inc/nebula-agent/pkg/proto"
+	"github.com/vesoft-inc/nebula-br/pkg/clients"
+	"github.com/vesoft-inc/nebula-br/pkg/config"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+	"github.com/vesoft-inc/nebula-go/v3/nebula/meta"
+)
+
+const readyInterval = 3 * time.Second
+
+// waitReady polls the probe until it succeeds or the ready timeout is exceeded
+func (r *Restore) waitReady(name string, probe func() error) error {
+	logger := log.WithField("probe", name)
+	if r.cfg.ReadyTimeout <= 0 {
+		logger.Warn("Ready timeout is 0, do not wait for the services to be ready.")
+		return nil
+	}
+
+	deadline := time.Now().Add(r.cfg.ReadyTimeout)
+	for {
+		err := probe()
+		if err == nil {
+			logger.Info("Services are ready.")
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s are not ready in %s: %w", name, r.cfg.ReadyTimeout, err)
+		}
+		logger.WithError(err).Info("Services are not ready yet, wait a moment.")
+
+		select {
+		case <-time.After(readyInterval):
+		case <-r.ctx.Done():
+			return r.ctx.Err()
+		}
+	}
+}
+
+// running checks the services are running by their agents
+func (r *Restore) running(services []*meta.ServiceInfo) error {
+	for _, s := range services {
+		agent, err := r.agentMgr.GetAgentFor(s.GetAddr())
+		if err != nil {
+			return fmt.Errorf("get agent for %s failed: %w", utils.StringifyAddr(s.GetAddr()), err)
+		}
+		resp, err := agent.ServiceStatus(&pb.ServiceStatusRequest{
+			Role: utils.ToRole(s.GetRole()),
+			Dir:  string(s.GetDir().GetRoot()),
+		})
+		if err != nil {
+			return fmt.Errorf("get status of %s[%s] failed: %w",
+				s.GetRole().String(), utils.StringifyAddr(s.GetAddr()), err)
+		}
+		if resp.Status != pb.Status_RUNNING {
+			return fmt.Errorf("%s[%s] is %s", s.GetRole().String(), utils.StringifyAddr(s.GetAddr()), resp.Status.String())
+		}
+	}
+	return nil
+}
+
+// newClusterMeta connects the metads in cluster, instead of the ones in flag
+// which may not be all restarted.
+func (r *Restore) newClusterMeta(conn *config.ConnConfig) (*clients.NebulaMeta, error) {
+	addrs := make([]string, 0)
+	for _, s := range r.hosts.GetMetas() {
+		addrs = append(addrs, utils.StringifyAddr(s.GetAddr()))
+	}
+	return clients.NewMeta(strings.Join(addrs, ","), conn)
+}
+
+// newProbeMeta connects the metads in cluster, without retry since it is polled
+func (r *Restore) newProbeMeta() (*clients.NebulaMeta, error) {
+	return r.newClusterMeta(r.cfg.Conn.NoRetry())
+}
+
+// hostLister lists the hosts of a role in meta service, it is clients.NebulaMeta except in tests
+type hostLister interface {
+	ListHosts(typ meta.ListHostType) ([]*meta.HostItem, error)
+}
+
+// online checks all the services in hosts of the role are online in meta service
+func online(m hostLister, typ meta.ListHostType, services []*meta.ServiceInfo) ([]*meta.HostItem, error) {
+	items, err := m.ListHosts(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make(map[string]meta.HostStatus)
+	for _, h := range items {
+		status[utils.StringifyAddr(h.GetHostAddr())] = h.GetStatus()
+	}
+	for _, s := range services {
+		addr := utils.StringifyAddr(s.GetAddr())
+		st, ok := status[addr]
+		if !ok {
+			return nil, fmt.Errorf("%s[%s] is not registered in meta service", s.GetRole().String(), addr)
+		}
+		if st != meta.HostStatus_ONLINE {
+			return nil, fmt.Errorf("%s[%s] is %s", s.GetRole().String(), addr, st.String())
+		}
+	}
+	return items, nil
+}
+
+// leaderless returns the partitions without leader, which are in format {space}:{part}
+func leaderless(storages []*meta.HostItem) []string {
+	leaders := make(map[string]bool)
+	for _, h := range storages {
+		for space, parts := range h.GetLeaderParts() {
+			for _, p := range parts {
+				leaders[fmt.Sprintf("%s:%d", space, p)] = true
+			}
+		}
+	}
+
+	var missing []string
+	seen := make(map[string]bool)
+	for _, h := range storages {
+		for space, parts := range h.GetAllParts() {
+			for _, p := range parts {
+				part := fmt.Sprintf("%s:%d", space, p)
+				if !leaders[part] && !seen[part] {
+					seen[part] = true
+					missing = append(missing, part)
+				}
+			}
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// waitMetaReady waits for the metads running and the meta leader elected, before restoring meta
+func (r *Restore) waitMetaReady() error {
+	return r.waitReady("metad", func() error {
+		if err := r.running(r.hosts.GetMetas()); err != nil {
+			return err
+		}
+
+		m, err := r.newProbeMeta()
+		if err != nil {
+			return err
+		}
+		defer m.Close()
+		_, err = m.ListHosts(meta.ListHostType_META)
+		if err != nil {
+			return fmt.Errorf("meta leader is not elected: %w", err)
+		}
+		return nil
+	})
+}
+
+// waitClusterReady waits for all the storaged and graphd online, and every partition has a leader
+func (r *Restore) waitClusterReady() error {
+	return r.waitReady("storaged and graphd", func() error {
+		if err := r.running(append(r.hosts.GetStorages(), r.hosts.GetGraphs()...)); err != nil {
+			return err
+		}
+
+		m, err := r.newProbeMeta()
+		if err != nil {
+			return err
+		}
+		defer m.Close()
+		storages, err := online(m, meta.ListHostType_STORAGE, r.hosts.GetStorages())
+		if err != nil {
+			return err
+		}
+		if _, err := online(m, meta.ListHostType_GRAPH, r.hosts.GetGraphs()); err != nil {
+			return err
+		}
+
+		if missing := leaderless(storages); len(missing) != 0 {
+			if len(missing) > 10 {
+				missing = append(missing[:10], "...")
+			}
+			return fmt.Errorf("partitions without leader: %s", strings.Join(missing, ", "))
+		}
+		return nil
+	})
+}
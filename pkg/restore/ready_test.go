@@ -0,0 +1,149 @@
+package restore
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vesoft-inc/nebula-go/v3/nebula"
+	"github.com/vesoft-inc/nebula-go/v3/nebula/meta"
+)
+
+type fakeLister struct {
+	items []*meta.HostItem
+	err   error
+}
+
+func (f *fakeLister) ListHosts(typ meta.ListHostType) ([]*meta.HostItem, error) {
+	return f.items, f.err
+}
+
+func hostItem(host string, status meta.HostStatus, leaders, all map[string][]nebula.PartitionID) *meta.HostItem {
+	return &meta.HostItem{
+		HostAddr:    &nebula.HostAddr{Host: host, Port: 9779},
+		Status:      status,
+		LeaderParts: leaders,
+		AllParts:    all,
+	}
+}
+
+func TestOnline(t *testing.T) {
+	assert := assert.New(t)
+
+	storage := func(host string) *meta.ServiceInfo {
+		return &meta.ServiceInfo{Role: meta.HostRole_STORAGE, Addr: &nebula.HostAddr{Host: host, Port: 9779}}
+	}
+	tests := []struct {
+		name     string
+		items    []*meta.HostItem
+		err      error
+		services []*meta.ServiceInfo
+		failed   string
+	}{
+		{
+			name: "all online",
+			items: []*meta.HostItem{
+				hostItem("192.168.0.1", meta.HostStatus_ONLINE, nil, nil),
+				hostItem("192.168.0.2", meta.HostStatus_ONLINE, nil, nil),
+			},
+			services: []*meta.ServiceInfo{storage("192.168.0.1"), storage("192.168.0.2")},
+		},
+		{
+			name: "extra host is ignored",
+			items: []*meta.HostItem{
+				hostItem("192.168.0.1", meta.HostStatus_ONLINE, nil, nil),
+				hostItem("192.168.0.9", meta.HostStatus_OFFLINE, nil, nil),
+			},
+			services: []*meta.ServiceInfo{storage("192.168.0.1")},
+		},
+		{
+			name: "offline",
+			items: []*meta.HostItem{
+				hostItem("192.168.0.1", meta.HostStatus_ONLINE, nil, nil),
+				hostItem("192.168.0.2", meta.HostStatus_OFFLINE, nil, nil),
+			},
+			services: []*meta.ServiceInfo{storage("192.168.0.1"), storage("192.168.0.2")},
+			failed:   "192.168.0.2:9779",
+		},
+		{
+			name:     "not registered",
+			items:    []*meta.HostItem{hostItem("192.168.0.1", meta.HostStatus_ONLINE, nil, nil)},
+			services: []*meta.ServiceInfo{storage("192.168.0.1"), storage("192.168.0.2")},
+			failed:   "192.168.0.2:9779] is not registered",
+		},
+		{
+			name:     "list hosts failed",
+			err:      fmt.Errorf("meta leader changed"),
+			services: []*meta.ServiceInfo{storage("192.168.0.1")},
+			failed:   "meta leader changed",
+		},
+	}
+
+	for _, tt := range tests {
+		items, err := online(&fakeLister{items: tt.items, err: tt.err}, meta.ListHostType_STORAGE, tt.services)
+		if tt.failed != "" {
+			assert.NotNil(err, tt.name)
+			if err != nil {
+				assert.Contains(err.Error(), tt.failed, tt.name)
+			}
+			continue
+		}
+		assert.Nil(err, tt.name)
+		assert.Equal(tt.items, items, tt.name)
+	}
+}
+
+func TestLeaderless(t *testing.T) {
+	assert := assert.New(t)
+
+	parts := func(ids ...nebula.PartitionID) []nebula.PartitionID { return ids }
+	tests := []struct {
+		name     string
+		storages []*meta.HostItem
+		missing  []string
+	}{
+		{
+			name:     "no storage",
+			storages: nil,
+			missing:  nil,
+		},
+		{
+			name: "all parts have leader",
+			storages: []*meta.HostItem{
+				hostItem("192.168.0.1", meta.HostStatus_ONLINE,
+					map[string][]nebula.PartitionID{"basic": parts(1)},
+					map[string][]nebula.PartitionID{"basic": parts(1, 2)}),
+				hostItem("192.168.0.2", meta.HostStatus_ONLINE,
+					map[string][]nebula.PartitionID{"basic": parts(2)},
+					map[string][]nebula.PartitionID{"basic": parts(1, 2)}),
+			},
+			missing: nil,
+		},
+		{
+			name: "replicated parts are reported once and sorted",
+			storages: []*meta.HostItem{
+				hostItem("192.168.0.1", meta.HostStatus_ONLINE,
+					map[string][]nebula.PartitionID{"basic": parts(1)},
+					map[string][]nebula.PartitionID{"basic": parts(1, 3, 2), "nba": parts(1)}),
+				hostItem("192.168.0.2", meta.HostStatus_ONLINE,
+					nil,
+					map[string][]nebula.PartitionID{"basic": parts(1, 2, 3), "nba": parts(1)}),
+			},
+			missing: []string{"basic:2", "basic:3", "nba:1"},
+		},
+		{
+			name: "leader of the same part id in another space does not count",
+			storages: []*meta.HostItem{
+				hostItem("192.168.0.1", meta.HostStatus_ONLINE,
+					map[string][]nebula.PartitionID{"nba": parts(1)},
+					map[string][]nebula.PartitionID{"basic": parts(1), "nba": parts(1)}),
+			},
+			missing: []string{"basic:1"},
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(tt.missing, leaderless(tt.storages), tt.name)
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	_ "os"
+	"path"
 	"path/filepath"
 	"reflect"
-	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -15,13 +17,16 @@ import (
 	"github.com/vesoft-inc/nebula-agent/pkg/storage"
 	"github.com/vesoft-inc/nebula-br/pkg/clients"
 	"github.com/vesoft-inc/nebula-br/pkg/config"
+	"github.com/vesoft-inc/nebula-br/pkg/progress"
+	"github.com/vesoft-inc/nebula-br/pkg/report"
 	"github.com/vesoft-inc/nebula-br/pkg/utils"
+	"github.com/vesoft-inc/nebula-br/pkg/validate"
 	"github.com/vesoft-inc/nebula-go/v3/nebula"
 	"github.com/vesoft-inc/nebula-go/v3/nebula/meta"
 )
 
 func GetBackupSuffix() string {
-	return fmt.Sprintf("_old_%d", time.Now().Unix())
+	return fmt.Sprintf("%s%d", backupSuffixPrefix, time.Now().Unix())
 }
 
 type Restore struct {
@@ -35,6 +40,17 @@ type Restore struct {
 	rootUri    string
 	backupName string
 	backSuffix string
+
+	refs         *utils.BackupRefs // only for incremental backup
+	storagePairs []*config.StoragePair
+	spaces       []nebula.GraphSpaceID // restored by space dirs, empty if the whole data dirs are replaced
+	rateLimit    *rateLimit            // nil if not limited
+	sizes        map[string]int64      // {backupName}/{path in manifest} -> size, nil if unknown
+
+	progress *progress.Progress
+
+	journalMu sync.Mutex
+	journal   *Journal
 }
 
 func NewRestore(ctx context.Context, cfg *config.RestoreConfig) (*Restore, error) {
@@ -43,7 +59,7 @@ func NewRestore(ctx context.Context, cfg *config.RestoreConfig) (*Restore, error
 		return nil, fmt.Errorf("create storage failed: %w", err)
 	}
 
-	client, err := clients.NewMeta(cfg.MetaAddr)
+	client, err := clients.NewMeta(cfg.MetaAddr, cfg.Conn)
 	if err != nil {
 		return nil, fmt.Errorf("create meta client failed: %w", err)
 	}
@@ -64,12 +80,64 @@ func NewRestore(ctx context.Context, cfg *config.RestoreConfig) (*Restore, error
 		sto:        sto,
 		meta:       client,
 		hosts:      hosts,
-		agentMgr:   clients.NewAgentManager(ctx, hosts),
+		agentMgr:   clients.NewAgentManager(ctx, hosts, cfg.Conn),
 		rootUri:    cfg.Backend.Uri(),
 		backupName: cfg.BackupName,
 	}, nil
 }
 
+// NewRestoreFromJournal creates restore by the journal of an interrupted restore, the
+// cluster topology is taken from journal since the meta service may have been stopped.
+func NewRestoreFromJournal(ctx context.Context, cfg *config.RestoreConfig) (*Restore, error) {
+	sto, err := storage.New(cfg.Backend)
+	if err != nil {
+		return nil, fmt.Errorf("create storage failed: %w", err)
+	}
+
+	r := &Restore{
+		ctx:        ctx,
+		cfg:        cfg,
+		sto:        sto,
+		rootUri:    cfg.Backend.Uri(),
+		backupName: cfg.BackupName,
+	}
+	r.journal, err = r.loadJournal()
+	if err != nil {
+		return nil, fmt.Errorf("load restore journal failed: %w", err)
+	}
+	if r.backupName == "" {
+		// only the journal file is specified
+		r.backupName = r.journal.BackupName
+		cfg.BackupName = r.journal.BackupName
+	} else if r.journal.BackupName != r.backupName {
+		return nil, fmt.Errorf("journal is for backup %s, but not %s", r.journal.BackupName, r.backupName)
+	}
+
+	r.hosts, err = r.journal.hosts()
+	if err != nil {
+		return nil, fmt.Errorf("parse cluster topology in journal failed: %w", err)
+	}
+	r.storagePairs, err = r.journal.storagePairs(r.hosts)
+	if err != nil {
+		return nil, fmt.Errorf("parse storage pairs in journal failed: %w", err)
+	}
+	r.agentMgr = clients.NewAgentManager(ctx, r.hosts, cfg.Conn)
+	r.backSuffix = r.journal.BackSuffix
+	r.spaces = r.journal.Spaces
+
+	// meta client is only used to drop spaces, which is before the cluster stopped
+	if !r.journal.AllSpaces && r.journal.Phase < PhaseSpacesDropped {
+		if cfg.MetaAddr == "" {
+			return nil, fmt.Errorf("spaces in journal have not been dropped, --%s should be specified", config.FlagMetaAddr)
+		}
+		r.meta, err = clients.NewMeta(cfg.MetaAddr, cfg.Conn)
+		if err != nil {
+			return nil, fmt.Errorf("create meta client failed: %w", err)
+		}
+	}
+	return r, nil
+}
+
 func (r *Restore) checkPhysicalTopology(info map[nebula.GraphSpaceID]*meta.SpaceBackupInfo) error {
 	var (
 		backupPaths    = make(map[int]int)
@@ -99,11 +167,13 @@ func (r *Restore) checkPhysicalTopology(info map[nebula.GraphSpaceID]*meta.Space
 	return nil
 }
 
-func (r *Restore) checkAndDropSpaces(info map[nebula.GraphSpaceID]*meta.SpaceBackupInfo) error {
+// checkSpaces checks the spaces to restore, and returns those already exist in cluster
+func (r *Restore) checkSpaces(info map[nebula.GraphSpaceID]*meta.SpaceBackupInfo) ([]string, error) {
+	var existed []string
 	for sid, backup := range info {
 		resp, err := r.meta.GetSpace(backup.Space.SpaceName)
 		if err != nil {
-			return fmt.Errorf("get info of space %s failed: %w", string(backup.Space.SpaceName), err)
+			return nil, fmt.Errorf("get info of space %s failed: %w", string(backup.Space.SpaceName), err)
 		}
 
 		if resp.GetCode() == nebula.ErrorCode_E_SPACE_NOT_FOUND {
@@ -111,15 +181,24 @@ func (r *Restore) checkAndDropSpaces(info map[nebula.GraphSpaceID]*meta.SpaceBac
 		}
 		if resp.GetCode() == nebula.ErrorCode_SUCCEEDED {
 			if resp.Item.SpaceID != sid {
-				return fmt.Errorf("space to restore already exist and the space id is not consistent, name: %s, backup: %d, cluster: %d",
+				return nil, fmt.Errorf("space to restore already exist and the space id is not consistent, name: %s, backup: %d, cluster: %d",
 					string(backup.Space.SpaceName), sid, resp.Item.SpaceID)
 			}
+			existed = append(existed, string(backup.Space.SpaceName))
 		} else {
-			return fmt.Errorf("get info of space %s failed: %s",
+			return nil, fmt.Errorf("get info of space %s failed: %s",
 				string(backup.Space.SpaceName), resp.GetCode().String())
 		}
 	}
 
+	return existed, nil
+}
+
+func (r *Restore) checkAndDropSpaces(info map[nebula.GraphSpaceID]*meta.SpaceBackupInfo) error {
+	if _, err := r.checkSpaces(info); err != nil {
+		return err
+	}
+
 	for _, backup := range info {
 		err := r.meta.DropSpace(backup.Space.SpaceName, true)
 		if err != nil {
@@ -130,8 +209,41 @@ func (r *Restore) checkAndDropSpaces(info map[nebula.GraphSpaceID]*meta.SpaceBac
 	return nil
 }
 
+// moveOriginal moves the origin data dir to the backup path, it is skipped if the backup
+// path already exists, which means it has been moved by an interrupted restore before.
+func moveOriginal(agent *clients.NebulaAgent, opath, bpath string) error {
+	res, err := agent.ExistDir(&pb.ExistDirRequest{Path: bpath})
+	if err != nil {
+		return fmt.Errorf("check %s exist failed: %w", bpath, err)
+	}
+	if res.Exist {
+		log.WithField("backup path", bpath).Info("Origin data path has been moved, skip it.")
+		return nil
+	}
+
+	req := &pb.MoveDirRequest{
+		SrcPath: opath,
+		DstPath: bpath,
+	}
+	_, err = agent.MoveDir(req)
+	if err != nil {
+		return fmt.Errorf("move dir from %s to %s failed: %w", opath, bpath, err)
+	}
+	return nil
+}
+
 func (r *Restore) backupOriginal(allspaces bool) error {
-	r.backSuffix = GetBackupSuffix()
+	if r.backSuffix == "" {
+		r.backSuffix = GetBackupSuffix()
+
+		// record the suffix before moving, so that the moved dirs could always be found
+		r.journalMu.Lock()
+		r.journal.BackSuffix = r.backSuffix
+		r.journalMu.Unlock()
+		if err := r.saveJournal(true); err != nil {
+			return fmt.Errorf("save restore journal failed: %w", err)
+		}
+	}
 
 	for _, s := range r.hosts.GetStorages() {
 		agent, err := r.agentMgr.GetAgentFor(s.GetAddr())
@@ -142,20 +254,23 @@ func (r *Restore) backupOriginal(allspaces bool) error {
 
 		logger := log.WithField("addr", utils.StringifyAddr(s.GetAddr()))
 		for _, d := range s.Dir.Data {
-			opath := filepath.Join(string(d), "nebula")
-			bpath := fmt.Sprintf("%s%s", opath, r.backSuffix)
-			req := &pb.MoveDirRequest{
-				SrcPath: opath,
-				DstPath: bpath,
-			}
-			_, err = agent.MoveDir(req)
-			if err != nil {
-				return fmt.Errorf("move dir from %s to %s failed: %w", opath, bpath, err)
+			for _, opath := range r.dataPaths(d) {
+				bpath := fmt.Sprintf("%s%s", opath, r.backSuffix)
+				err = moveOriginal(agent, opath, bpath)
+				// the space dir may not exist in every data path
+				if err != nil && !(len(r.spaces) != 0 && utils.IsNotExist(err)) {
+					return err
+				}
+
+				logger.WithField("origin path", opath).
+					WithField("backup path", bpath).
+					Info("Backup origin storage data path successfully.")
 			}
+		}
 
-			logger.WithField("origin path", opath).
-				WithField("backup path", bpath).
-				Info("Backup origin storage data path successfully.")
+		// the cluster is kept when only some spaces are restored
+		if len(r.spaces) != 0 {
+			continue
 		}
 
 		// move cluster.id
@@ -190,14 +305,9 @@ func (r *Restore) backupOriginal(allspaces bool) error {
 
 			opath := fmt.Sprintf("%s/nebula", string(m.Dir.Data[0]))
 			bpath := fmt.Sprintf("%s%s", opath, r.backSuffix)
-
-			req := &pb.MoveDirRequest{
-				SrcPath: opath,
-				DstPath: bpath,
-			}
-			_, err = agent.MoveDir(req)
+			err = moveOriginal(agent, opath, bpath)
 			if err != nil {
-				return fmt.Errorf("move dir from %s to %s failed: %w", opath, bpath, err)
+				return err
 			}
 
 			log.WithField("addr", utils.StringifyAddr(m.GetAddr())).
@@ -218,105 +328,223 @@ func (r *Restore) downloadMeta() error {
 		return fmt.Errorf("get storage backend for %s failed: %w", externalUri, err)
 	}
 
-	// download meta backup files to every meta service
+	// download meta backup files to every meta service concurrently, the agents are shared
+	// with the other steps, and the downloading not started will be skipped once any fails
+	pool := utils.NewWorkerPool(r.ctx, r.cfg.Concurrency)
 	for _, s := range r.hosts.GetMetas() {
-		agent, err := r.agentMgr.GetAgentFor(s.GetAddr())
+		s := s
+		name := fmt.Sprintf("metad[%s]", utils.StringifyAddr(s.GetAddr()))
+		if r.downloaded(name) {
+			log.WithField("task", name).Info("Meta data has been downloaded, skip it.")
+			continue
+		}
+		pool.Go(name, func(ctx context.Context) error {
+			agent, err := r.agentMgr.GetAgentFor(s.GetAddr())
+			if err != nil {
+				return fmt.Errorf("get agent for metad %s failed: %w",
+					utils.StringifyAddr(s.GetAddr()), err)
+			}
+
+			// meta kv data path: {nebulaData}/meta
+			localDir := string(s.Dir.Data[0])
+			req := &pb.DownloadFileRequest{
+				SourceBackend: backend,
+				TargetPath:    localDir,
+				Recursively:   true,
+			}
+			_, err = agent.DownloadFile(req)
+			if err != nil {
+				return fmt.Errorf("download meta files from %s to %s failed: %w", externalUri, localDir, err)
+			}
+			return r.markDownloaded(name)
+		})
+	}
+
+	return pool.Wait()
+}
+
+func (r *Restore) downloadStorage(bakMeta *meta.BackupMeta) error {
+	// download from previous to current, every data path of each host concurrently
+	pool := utils.NewWorkerPool(r.ctx, r.cfg.Concurrency)
+	// {backupRoot}/{backupName}/data/{addr}/data{0..n}/
+	storageUri, _ := utils.UriJoin(r.rootUri, r.backupName, "data")
+
+	var total int
+	var totalBytes int64
+	for _, pair := range r.storagePairs {
+		for i, p := range pair.Paths {
+			name := fmt.Sprintf("storaged[%s]:data%d", utils.StringifyAddr(pair.To.GetAddr()), p)
+			if !r.downloaded(name) {
+				total++
+				totalBytes += r.taskSize(bakMeta, pair, i)
+			}
+		}
+	}
+	r.progress.Total(total, totalBytes)
+
+	for _, pair := range r.storagePairs {
+		for i, p := range pair.Paths {
+			pair, i, p := pair, i, p
+			s := pair.To
+			name := fmt.Sprintf("storaged[%s]:data%d", utils.StringifyAddr(s.GetAddr()), p)
+			if r.downloaded(name) {
+				log.WithField("task", name).Info("Storage data has been downloaded, skip it.")
+				continue
+			}
+			dirs := r.downloadDirs(bakMeta, pair, i)
+			pool.Go(name, func(ctx context.Context) error {
+				if err := r.downloadData(ctx, pair, dirs, p, storageUri); err != nil {
+					localDir := filepath.Join(string(s.Dir.Data[p]), "nebula")
+					return &utils.TaskError{Host: utils.StringifyAddr(s.GetAddr()), Path: localDir, Err: err}
+				}
+				if err := r.markDownloaded(name); err != nil {
+					return err
+				}
+				r.progress.Done(name, r.taskSize(bakMeta, pair, i))
+				return nil
+			})
+		}
+	}
+
+	return pool.Wait()
+}
+
+// downloadData downloads the dirs in a data path of the storaged in backup to the p-th data path of the target
+func (r *Restore) downloadData(ctx context.Context, pair *config.StoragePair, dirs []*spaceDir,
+	p int, storageUri string) error {
+	s := pair.To
+	agent, err := r.agentMgr.GetAgentFor(s.GetAddr())
+	if err != nil {
+		return fmt.Errorf("get agent for storaged %s failed: %w",
+			utils.StringifyAddr(s.GetAddr()), err)
+	}
+
+	for _, dir := range dirs {
+		// {backupRoot}/{backupName}/data/{addr}/data{0..n}/[{spaceId}/]
+		externalUri, _ := utils.UriJoin(storageUri, dir.prefix)
+		// avoid agent.DownloadFile prefix bugs
+		externalUri += "/"
+
+		backend, err := r.sto.GetDir(ctx, externalUri)
 		if err != nil {
-			return fmt.Errorf("get agent for metad %s failed: %w",
-				utils.StringifyAddr(s.GetAddr()), err)
+			return fmt.Errorf("get storage backend for %s failed: %w", externalUri, err)
+		}
+		// {nebulaDataPath}/storage/nebula[/{spaceId}]
+		localDir := filepath.Join(string(s.Dir.Data[p]), dir.local)
+
+		// {backupName}/data/{addr}/data{0..n}[/{spaceId}]
+		err = r.wait(ctx, s.GetAddr().GetHost(), path.Join(r.backupName, "data", dir.prefix))
+		if err != nil {
+			return err
 		}
 
-		// meta kv data path: {nebulaData}/meta
-		localDir := string(s.Dir.Data[0])
 		req := &pb.DownloadFileRequest{
 			SourceBackend: backend,
 			TargetPath:    localDir,
 			Recursively:   true,
 		}
+
 		_, err = agent.DownloadFile(req)
 		if err != nil {
-			return fmt.Errorf("download meta files from %s to %s failed: %w", externalUri, localDir, err)
+			return fmt.Errorf("download from %s to %s:%s failed: %w",
+				externalUri, localDir, utils.StringifyAddr(s.GetAddr()), err)
 		}
-	}
+		log.WithField("addr", utils.StringifyAddr(s.GetAddr())).
+			WithField("external", externalUri).
+			WithField("local", localDir).Info("Download storage data successfully.")
 
+		err = r.downloadInherited(ctx, agent, dir.prefix, localDir)
+		if err != nil {
+			return fmt.Errorf("download inherited files to %s:%s failed: %w",
+				utils.StringifyAddr(s.GetAddr()), localDir, err)
+		}
+	}
 	return nil
 }
 
-func (r *Restore) downloadStorage(backup *meta.BackupMeta) (map[string]string, error) {
-	// TODO(spw): only support same ip now, by sorting address
-	// could match by label(or id) in the future, now suppose the label is ip.
+// Report fills the report with the hosts, phases and transferred bytes of restore,
+// the bytes are only known if the backup has manifest
+func (r *Restore) Report(rep *report.Report) {
+	rep.BackupName = r.backupName
+	rep.Hosts = r.hosts.Hosts()
+	rep.Phases = r.progress.Records()
+	var bytes int64
+	for _, record := range rep.Phases {
+		bytes += record.DoneBytes
+	}
+	if bytes > 0 {
+		rep.SetBytes(bytes)
+	} else {
+		rep.SizeUnknown = "unknown, no manifest"
+	}
+	if r.cfg.PaceRate > 0 {
+		rep.PaceRate = utils.FormatRate(r.cfg.PaceRate)
+	}
+	if r.cfg.HostPaceRate > 0 {
+		rep.HostPaceRate = utils.FormatRate(r.cfg.HostPaceRate)
+	}
+}
 
-	// current cluster storage service list
-	currList := r.hosts.GetStorages()
-	sort.Slice(currList, func(i, j int) bool {
-		if currList[i].Addr.Host != currList[j].Addr.Host {
-			return currList[i].Addr.Host < currList[j].Addr.Host
+// taskSize returns the size of the dirs to download in the i-th data path of the storaged in backup,
+// including the sst files inherited from its base chain, it returns 0 if the sizes are unknown
+func (r *Restore) taskSize(bakMeta *meta.BackupMeta, pair *config.StoragePair, i int) int64 {
+	var size int64
+	for _, dir := range r.downloadDirs(bakMeta, pair, i) {
+		size += r.sizeOf(path.Join(r.backupName, "data", dir.prefix))
+		if r.refs == nil {
+			continue
 		}
-		return currList[i].Addr.Port < currList[j].Addr.Port
-	})
-
-	// previous backup storage service list
-	prevMap := make(map[string]*nebula.HostAddr)
-	for _, sb := range backup.SpaceBackups {
-		for _, hb := range sb.HostBackups {
-			prevMap[utils.StringifyAddr(hb.GetHost())] = hb.GetHost()
+		for d, files := range r.refs.Files {
+			if !strings.HasPrefix(d, dir.prefix+"/") {
+				continue
+			}
+			for f, owner := range files {
+				size += r.sizes[path.Join(owner, "data", d, f)]
+			}
 		}
 	}
-	prevList := make([]*nebula.HostAddr, 0, len(prevMap))
-	for _, addr := range prevMap {
-		prevList = append(prevList, addr)
+	return size
+}
+
+// downloadInherited downloads the sst files which the incremental backup shares with
+// the backups in its base chain, prefix is the dir in backup like {addr}/data{0..n}[/{spaceId}]
+func (r *Restore) downloadInherited(ctx context.Context, agent *clients.NebulaAgent, prefix string, localDir string) error {
+	if r.refs == nil {
+		return nil
 	}
-	sort.Slice(prevList, func(i, j int) bool {
-		if prevList[i].Host != prevList[j].Host {
-			return prevList[i].Host < prevList[j].Host
-		}
-		return prevList[i].Port < prevList[j].Port
-	})
 
-	// download from previous to current one host by another
-	serviceMap := make(map[string]string)
-	// {backupRoot}/{backupName}/data/{addr}/data{0..n}/
-	storageUri, _ := utils.UriJoin(r.rootUri, r.backupName, "data")
-	for idx, s := range currList {
-		agent, err := r.agentMgr.GetAgentFor(s.GetAddr())
-		if err != nil {
-			return nil, fmt.Errorf("get agent for storaged %s failed: %w",
-				utils.StringifyAddr(s.GetAddr()), err)
+	for dir, files := range r.refs.Files {
+		if !strings.HasPrefix(dir, prefix+"/") {
+			continue
 		}
 
-		logger := log.WithField("addr", utils.StringifyAddr(s.GetAddr()))
-		for i, d := range s.Dir.Data {
-			// {backupRoot}/{backupName}/data/{addr}/data{0..n}/
-			externalUri, _ := utils.UriJoin(storageUri, utils.StringifyAddr(prevList[idx]), fmt.Sprintf("data%d", i))
-			// avoid agent.DownloadFile prefix bugs
-			externalUri += "/"
-
-			backend, err := r.sto.GetDir(r.ctx, externalUri)
+		// {nebulaDataPath}/storage/nebula/{spaceId}/data
+		localDataDir := filepath.Join(localDir, strings.TrimPrefix(dir, prefix+"/"))
+		for f, owner := range files {
+			// {backupRoot}/{ownerName}/data/{addr}/data{0..n}/{spaceId}/data/{file}
+			externalUri, _ := utils.UriJoin(r.rootUri, owner, "data", dir, f)
+			if err := r.wait(ctx, agent.GetAddr().GetHost(), path.Join(owner, "data", dir, f)); err != nil {
+				return err
+			}
+			backend, err := r.sto.GetDir(ctx, externalUri)
 			if err != nil {
-				return nil, fmt.Errorf("get storage backend for %s failed: %w", externalUri, err)
+				return fmt.Errorf("get storage backend for %s failed: %w", externalUri, err)
 			}
-			// {nebulaDataPath}/storage/nebula
-			localDir := filepath.Join(string(d), "nebula")
 
 			req := &pb.DownloadFileRequest{
 				SourceBackend: backend,
-				TargetPath:    localDir,
-				Recursively:   true,
+				TargetPath:    filepath.Join(localDataDir, f),
+				Recursively:   false,
 			}
-
 			_, err = agent.DownloadFile(req)
 			if err != nil {
-				return nil, fmt.Errorf("download from %s to %s:%s failed: %w",
-					externalUri, localDir, utils.StringifyAddr(s.GetAddr()), err)
+				return fmt.Errorf("download from %s to %s failed: %w", externalUri, req.TargetPath, err)
 			}
-			logger.WithField("external", externalUri).
-				WithField("local", localDir).Info("Download storage data successfully.")
 		}
-
-		serviceMap[utils.StringifyAddr(prevList[idx])] = utils.StringifyAddr(s.GetAddr())
+		log.WithField("dir", dir).WithField("files", len(files)).Info("Download inherited sst files successfully.")
 	}
 
-	return serviceMap, nil
+	return nil
 }
 
 func (r *Restore) startMetaService() error {
@@ -408,7 +636,7 @@ func (r *Restore) restoreMeta(backup *meta.BackupMeta, storageMap map[string]str
 			metaSsts = append(metaSsts, sstPath)
 		}
 
-		err := r.meta.RestoreMeta(meta.GetAddr(), addrMap, metaSsts)
+		err := clients.RestoreMeta(meta.GetAddr(), r.cfg.Conn, addrMap, metaSsts)
 		if err != nil {
 			return fmt.Errorf("restore meta service %s failed: %w",
 				utils.StringifyAddr(meta.GetAddr()), err)
@@ -495,14 +723,19 @@ func (r *Restore) cleanupOriginalData() error {
 
 		logger := log.WithField("addr", utils.StringifyAddr(s.GetAddr()))
 		for _, dir := range s.Dir.Data {
-			req := &pb.RemoveDirRequest{
-				Path: fmt.Sprintf("%s/nebula%s", string(dir), r.backSuffix),
-			}
-			_, err = agent.RemoveDir(req)
-			if err != nil {
-				return fmt.Errorf("remove storage data dir %s by agent failed: %w", req.Path, err)
+			for _, opath := range r.dataPaths(dir) {
+				req := &pb.RemoveDirRequest{
+					Path: fmt.Sprintf("%s%s", opath, r.backSuffix),
+				}
+				_, err = agent.RemoveDir(req)
+				if err != nil {
+					return fmt.Errorf("remove storage data dir %s by agent failed: %w", req.Path, err)
+				}
+				logger.WithField("path", req.Path).Info("Remove storage origin data successfully.")
 			}
-			logger.WithField("path", req.Path).Info("Remove storage origin data successfully.")
+		}
+		if len(r.spaces) != 0 {
+			continue
 		}
 
 		// remove backup cluster.id
@@ -518,26 +751,79 @@ func (r *Restore) cleanupOriginalData() error {
 	return nil
 }
 
-// backup_root/backup_name
-//   - meta
-//   - xxx.sst
-//   - ...
-//   - data
-//   - backup_name.meta
-func (r *Restore) Restore() error {
+// loadRefs loads the refs of incremental backup, which only keeps the sst files
+// do not exist in its base chain
+func (r *Restore) loadRefs(bakMeta *meta.BackupMeta) error {
+	if len(bakMeta.GetBaseBackupName()) == 0 {
+		return nil
+	}
+
+	var err error
+	r.refs, err = utils.DownloadRefs(r.ctx, r.sto, r.cfg.Backend.Uri(), r.cfg.BackupName, r.cfg.EncryptionKey)
+	if err != nil {
+		return fmt.Errorf("get refs of incremental backup failed: %w", err)
+	}
+	log.WithField("backup", r.cfg.BackupName).WithField("chain", r.refs.Chain).
+		Info("Restore from incremental backup.")
+	return nil
+}
+
+// prepare downloads the backup meta, and checks whether the backup could be restored
+// to this cluster, nothing in the cluster will be changed.
+func (r *Restore) prepare() (*meta.BackupMeta, error) {
 	logger := log.WithField("backup", r.cfg.BackupName)
 	// check backup dir existence
 	rootUri, err := utils.UriJoin(r.cfg.Backend.Uri(), r.cfg.BackupName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	exist := r.sto.ExistDir(r.ctx, rootUri)
 	if !exist {
-		return fmt.Errorf("backup dir %s does not exist", rootUri)
+		return nil, fmt.Errorf("backup dir %s does not exist", rootUri)
 	}
 	logger.WithField("uri", rootUri).Info("Check backup dir successfully.")
 
 	// download and parse backup meta file
+	bakMeta, err := utils.DownloadMetaFile(r.ctx, r.sto, r.cfg.Backend.Uri(), r.cfg.BackupName, r.cfg.EncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("get backup meta file failed: %w", err)
+	}
+
+	if err = r.loadRefs(bakMeta); err != nil {
+		return nil, err
+	}
+
+	// check this cluster's topology with info kept in backup meta,
+	// the host map will be checked when pairing storages if specified
+	if r.cfg.HostMap == nil {
+		err = r.checkPhysicalTopology(bakMeta.GetSpaceBackups())
+		if err != nil {
+			return nil, fmt.Errorf("physical topology not consistent: %w", err)
+		}
+	}
+	r.storagePairs, err = config.PairStorages(bakMeta, r.hosts.GetStorages(), r.cfg.HostMap)
+	if err != nil {
+		return nil, fmt.Errorf("pair storages in backup and cluster failed: %w", err)
+	}
+	// the storages are paired by all the spaces in backup, since all of them are in backup meta files
+	bakMeta, err = r.selectSpaces(bakMeta)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.loadRateLimit(); err != nil {
+		return nil, err
+	}
+
+	return bakMeta, nil
+}
+
+// backup_root/backup_name
+//   - meta
+//   - xxx.sst
+//   - ...
+//   - data
+//   - backup_name.meta
+func (r *Restore) Restore() error {
 	if err := utils.EnsureDir(utils.LocalTmpDir); err != nil {
 		return err
 	}
@@ -547,90 +833,198 @@ func (r *Restore) Restore() error {
 		}
 	}()
 
-	backupMetaName := fmt.Sprintf("%s.meta", r.cfg.BackupName)
-	metaUri, _ := utils.UriJoin(rootUri, backupMetaName)
-	tmpLocalPath := filepath.Join(utils.LocalTmpDir, backupMetaName)
-	err = r.sto.Download(r.ctx, tmpLocalPath, metaUri, false)
+	bakMeta, err := r.prepare()
 	if err != nil {
-		return fmt.Errorf("download %s to %s failed: %w", metaUri, tmpLocalPath, err)
-	}
-	bakMeta, err := utils.ParseMetaFromFile(tmpLocalPath)
-	if err != nil {
-		return fmt.Errorf("parse backup meta file %s failed: %w", tmpLocalPath, err)
+		return err
 	}
 
-	// check this cluster's topology with info kept in backup meta
-	err = r.checkPhysicalTopology(bakMeta.GetSpaceBackups())
-	if err != nil {
-		return fmt.Errorf("physical topology not consistent: %w", err)
+	r.journal = newJournal(r.backupName, bakMeta.AllSpaces, r.spaces, r.hosts, r.storagePairs)
+	r.journal.Storage = r.rootUri
+	if err = r.saveJournal(true); err != nil {
+		return fmt.Errorf("save restore journal failed: %w", err)
 	}
+	log.WithField("journal", r.cfg.JournalPath).Info("Create restore journal successfully.")
 
-	// if only restore some spaces, check and remove these spaces
-	if !bakMeta.AllSpaces {
-		err = r.checkAndDropSpaces(bakMeta.SpaceBackups)
-		if err != nil {
-			return fmt.Errorf("check and drop space failed: %w", err)
-		}
-		log.Info("Check and drop spaces successfully.")
-	}
+	return r.run(bakMeta)
+}
 
-	// stop cluster
-	err = r.stopCluster()
-	if err != nil {
-		return fmt.Errorf("stop cluster failed: %w", err)
+// Resume continues an interrupted restore from the phase recorded in journal
+func (r *Restore) Resume() error {
+	if r.journal.Phase >= PhaseFinished {
+		return fmt.Errorf("restore of %s is %s, nothing to resume", r.backupName, r.journal.Phase)
 	}
-	logger.Info("Stop cluster successfully.")
 
-	// backup original data
-	err = r.backupOriginal(bakMeta.AllSpaces)
-	if err != nil {
-		return fmt.Errorf("backup origin data path failed: %w", err)
+	if err := utils.EnsureDir(utils.LocalTmpDir); err != nil {
+		return err
 	}
-	logger.Info("Backup origin cluster data successfully.")
+	defer func() {
+		if err := utils.RemoveDir(utils.LocalTmpDir); err != nil {
+			log.WithError(err).Errorf("Remove tmp dir %s failed.", utils.LocalTmpDir)
+		}
+	}()
 
-	// download backup data from external storage to cluster
-	err = r.downloadMeta()
+	bakMeta, err := utils.DownloadMetaFile(r.ctx, r.sto, r.rootUri, r.backupName, r.cfg.EncryptionKey)
 	if err != nil {
-		return fmt.Errorf("download meta data to cluster failed: %w", err)
+		return fmt.Errorf("get backup meta file failed: %w", err)
 	}
-	log.Info("Download meta data to cluster successfully.")
-	storageMap, err := r.downloadStorage(bakMeta)
-	if err != nil {
-		return fmt.Errorf("download storage data to cluster failed: %w", err)
+	if err = r.loadRefs(bakMeta); err != nil {
+		return err
 	}
-	log.Info("Download storage data to cluster successfully.")
-
-	// start meta service first
-	err = r.startMetaService()
-	if err != nil {
-		return fmt.Errorf("start meta service failed: %w", err)
+	if err = r.loadRateLimit(); err != nil {
+		return err
 	}
-	time.Sleep(time.Second * 10)
-	log.Info("Start meta service successfully.")
-
-	// restore meta service by map
-	err = r.restoreMeta(bakMeta, storageMap)
-	if err != nil {
-		return fmt.Errorf("restore cluster meta failed: %w", err)
+	if len(r.spaces) != 0 {
+		bakMeta = filterSpaces(bakMeta, r.spaces)
 	}
-	log.Info("Restore meta service successfully.")
 
-	// start storage and graph service
-	err = r.startStorageService()
-	if err != nil {
-		return fmt.Errorf("start storage service failed: %w", err)
+	log.WithField("backup", r.backupName).WithField("phase", r.journal.Phase).
+		Info("Resume restore from the recorded phase.")
+	return r.run(bakMeta)
+}
+
+type restoreStep struct {
+	phase  Phase
+	name   string // shown in progress
+	action func() error
+}
+
+// run executes the restore steps after the phase recorded in journal,
+// the journal is saved after every step.
+func (r *Restore) run(bakMeta *meta.BackupMeta) error {
+	logger := log.WithField("backup", r.cfg.BackupName)
+	steps := []restoreStep{
+		{PhaseSpacesDropped, "drop spaces", func() error {
+			// if only restore some spaces, check and remove these spaces
+			if bakMeta.AllSpaces {
+				return nil
+			}
+			err := r.checkAndDropSpaces(bakMeta.SpaceBackups)
+			if err != nil {
+				return fmt.Errorf("check and drop space failed: %w", err)
+			}
+			log.Info("Check and drop spaces successfully.")
+			return nil
+		}},
+		{PhaseClusterStopped, "stop cluster", func() error {
+			err := r.stopCluster()
+			if err != nil {
+				return fmt.Errorf("stop cluster failed: %w", err)
+			}
+			logger.Info("Stop cluster successfully.")
+			return nil
+		}},
+		{PhaseOriginalMoved, "move original data", func() error {
+			err := r.backupOriginal(bakMeta.AllSpaces)
+			if err != nil {
+				return fmt.Errorf("backup origin data path failed: %w", err)
+			}
+			logger.Info("Backup origin cluster data successfully.")
+			return nil
+		}},
+		{PhaseDataDownloaded, "download data", func() error {
+			// download backup data from external storage to cluster
+			err := r.downloadMeta()
+			if err != nil {
+				return fmt.Errorf("download meta data to cluster failed: %w", err)
+			}
+			log.Info("Download meta data to cluster successfully.")
+			err = r.downloadStorage(bakMeta)
+			if err != nil {
+				return fmt.Errorf("download storage data to cluster failed: %w", err)
+			}
+			log.Info("Download storage data to cluster successfully.")
+			return nil
+		}},
+		{PhaseMetaStarted, "start meta service", func() error {
+			// start meta service first
+			err := r.startMetaService()
+			if err != nil {
+				return fmt.Errorf("start meta service failed: %w", err)
+			}
+			err = r.waitMetaReady()
+			if err != nil {
+				return fmt.Errorf("wait meta service ready failed: %w", err)
+			}
+			log.Info("Start meta service successfully.")
+			return nil
+		}},
+		{PhaseMetaRestored, "restore meta", func() error {
+			// restore meta service by map
+			err := r.restoreMeta(bakMeta, config.StorageMap(r.storagePairs))
+			if err != nil {
+				return fmt.Errorf("restore cluster meta failed: %w", err)
+			}
+			log.Info("Restore meta service successfully.")
+			return nil
+		}},
+		{PhaseServicesStarted, "start services", func() error {
+			// start storage and graph service
+			err := r.startStorageService()
+			if err != nil {
+				return fmt.Errorf("start storage service failed: %w", err)
+			}
+			err = r.startGraphService()
+			if err != nil {
+				return fmt.Errorf("start graph service failed: %w", err)
+			}
+			err = r.waitClusterReady()
+			if err != nil {
+				return fmt.Errorf("wait cluster ready failed: %w", err)
+			}
+			log.Info("Start storage and graph services successfully.")
+			return nil
+		}},
+		{PhaseValidated, "validate", func() error {
+			if !r.cfg.Validate {
+				return nil
+			}
+			m, err := r.newClusterMeta(r.cfg.Conn)
+			if err != nil {
+				return fmt.Errorf("create meta client for validation failed: %w", err)
+			}
+			defer m.Close()
+			err = validate.Spaces(m, bakMeta, config.StorageMap(r.storagePairs))
+			if err != nil {
+				return fmt.Errorf("validate restored cluster failed: %w", err)
+			}
+			log.Info("Validate restored cluster successfully.")
+			return nil
+		}},
+		{PhaseFinished, "clean up original data", func() error {
+			// after success restore, cleanup the backup data if needed
+			err := r.cleanupOriginalData()
+			if err != nil {
+				return fmt.Errorf("clean up origin data failed: %w", err)
+			}
+			log.Info("Cleanup origin data successfully.")
+			return nil
+		}},
 	}
-	err = r.startGraphService()
-	if err != nil {
-		return fmt.Errorf("start graph service failed: %w", err)
+
+	var todo []restoreStep
+	var names []string
+	for _, step := range steps {
+		if step.phase > r.journal.Phase {
+			todo = append(todo, step)
+			names = append(names, step.name)
+		}
 	}
-	log.Info("Start storage and graph services successfully.")
 
-	// after success restore, cleanup the backup data if needed
-	err = r.cleanupOriginalData()
-	if err != nil {
-		return fmt.Errorf("clean up origin data failed: %w", err)
+	r.progress = progress.New("restore", names)
+	err := r.runSteps(todo)
+	r.progress.Finish(err)
+	return err
+}
+
+func (r *Restore) runSteps(steps []restoreStep) error {
+	for _, step := range steps {
+		r.progress.Phase(step.name)
+		if err := step.action(); err != nil {
+			return err
+		}
+		if err := r.setPhase(step.phase); err != nil {
+			return fmt.Errorf("save restore journal failed: %w", err)
+		}
 	}
-	log.Info("Cleanup origin data successfully.")
 	return nil
 }
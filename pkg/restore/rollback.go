@@ -0,0 +1,336 @@
+package restore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	pb "github.com/vesoft-inc/nebula-agent/pkg/proto"
+	"github.com/vesoft-inc/nebula-agent/pkg/storage"
+	"github.com/vesoft-inc/nebula-br/pkg/clients"
+	"github.com/vesoft-inc/nebula-br/pkg/config"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+	"github.com/vesoft-inc/nebula-go/v3/nebula"
+	"github.com/vesoft-inc/nebula-go/v3/nebula/meta"
+)
+
+const (
+	backupSuffixPrefix = "_old_"
+	latestSuffix       = "latest"
+)
+
+// Rollback moves the origin data dirs left by a failed or interrupted restore back,
+// by the restore journal or by the suffix of the moved dirs.
+type Rollback struct {
+	ctx context.Context
+	cfg *config.RollbackConfig
+	sto storage.ExternalStorage
+}
+
+// NewRollback creates the rollback, the storage is only optional for --suffix latest,
+// without which the journals kept in backup dirs are not searched.
+func NewRollback(ctx context.Context, cfg *config.RollbackConfig) (*Rollback, error) {
+	rb := &Rollback{
+		ctx: ctx,
+		cfg: cfg,
+	}
+	if cfg.Backend == nil {
+		switch cfg.Suffix {
+		case "":
+			return nil, fmt.Errorf("no storage to rollback by journal, please specify it by --%s", config.FlagStorage)
+		case latestSuffix:
+			log.Warnf("No --%s is specified, only the local restore journals are searched for the latest suffix.", config.FlagStorage)
+			return rb, nil
+		default:
+			return nil, fmt.Errorf("--%s should be specified to rollback by --suffix %s", config.FlagStorage, cfg.Suffix)
+		}
+	}
+
+	sto, err := storage.New(cfg.Backend)
+	if err != nil {
+		return nil, fmt.Errorf("create storage failed: %w", err)
+	}
+	rb.sto = sto
+	return rb, nil
+}
+
+func (rb *Rollback) Rollback() error {
+	if rb.cfg.Suffix == "" {
+		return rb.byJournal()
+	}
+	return rb.bySuffix()
+}
+
+func (rb *Rollback) byJournal() error {
+	r, err := NewRestoreFromJournal(rb.ctx, &config.RestoreConfig{
+		MetaAddr:    rb.cfg.MetaAddr,
+		BackupName:  rb.cfg.BackupName,
+		Backend:     rb.cfg.Backend,
+		Conn:        rb.cfg.Conn,
+		JournalPath: rb.cfg.JournalPath,
+		Concurrency: 1,
+	})
+	if err != nil {
+		return err
+	}
+
+	f, err := NewFixFrom(r)
+	if err != nil {
+		return err
+	}
+	if err := rb.confirm(f); err != nil {
+		return err
+	}
+	return f.Rollback()
+}
+
+// confirm prints the dirs to remove and move back, which are only changed with --yes
+func (rb *Rollback) confirm(f *Fix) error {
+	plan, err := f.planData()
+	if err != nil {
+		return err
+	}
+	if len(plan) == 0 {
+		return nil
+	}
+
+	fmt.Printf("Rollback will stop the cluster and move back %d origin data dirs with suffix %s:\n", len(plan), f.backSuffix)
+	for _, d := range plan {
+		fmt.Printf("  %s\n", d)
+	}
+	if !rb.cfg.Yes {
+		return fmt.Errorf("rollback is not confirmed, the current data dirs above will be removed, rerun with --yes to continue")
+	}
+	return nil
+}
+
+// bySuffix discovers the origin data dirs with the suffix in every metad and storaged,
+// the cluster topology is listed from meta service, so it should be available.
+func (rb *Rollback) bySuffix() error {
+	if rb.cfg.MetaAddr == "" {
+		return fmt.Errorf("--%s should be specified to rollback by --suffix, the cluster is listed from meta service", config.FlagMetaAddr)
+	}
+	client, err := clients.NewMeta(rb.cfg.MetaAddr, rb.cfg.Conn)
+	if err != nil {
+		return fmt.Errorf("create meta client failed, try to rollback by restore journal: %w", err)
+	}
+
+	listRes, err := client.ListCluster()
+	if err != nil {
+		return fmt.Errorf("list cluster failed: %w", err)
+	}
+	hosts := &utils.NebulaHosts{}
+	err = hosts.LoadFrom(listRes)
+	if err != nil {
+		return fmt.Errorf("parse cluster response failed: %w", err)
+	}
+	spaces, err := client.ListSpaces()
+	if err != nil {
+		return fmt.Errorf("list spaces failed: %w", err)
+	}
+	sids := make([]nebula.GraphSpaceID, 0, len(spaces))
+	for _, s := range spaces {
+		sids = append(sids, s.GetId().GetSpaceID())
+	}
+	r := &Restore{
+		ctx:      rb.ctx,
+		hosts:    hosts,
+		agentMgr: clients.NewAgentManager(rb.ctx, hosts, rb.cfg.Conn),
+	}
+
+	suffixes := []string{rb.cfg.Suffix}
+	if rb.cfg.Suffix == latestSuffix {
+		suffixes = rb.journalSuffixes()
+		if len(suffixes) == 0 {
+			return fmt.Errorf("no suffix is recorded in restore journals, please specify it by --suffix")
+		}
+	} else if !strings.HasPrefix(rb.cfg.Suffix, backupSuffixPrefix) {
+		return fmt.Errorf("suffix %s should start with %s", rb.cfg.Suffix, backupSuffixPrefix)
+	}
+
+	// the suffixes are sorted from the latest
+	for _, suffix := range suffixes {
+		found, foundSpaces, err := r.discoverOriginal(suffix, sids)
+		if err != nil {
+			return err
+		}
+		if len(found) == 0 && len(foundSpaces) == 0 {
+			log.WithField("suffix", suffix).Info("No origin data dir with the suffix is found.")
+			continue
+		}
+		// the whole data dirs and the space dirs are not moved in the same restore
+		if len(found) != 0 && len(foundSpaces) != 0 {
+			return fmt.Errorf("both origin data dirs and space dirs with suffix %s are found, please move them back manually", suffix)
+		}
+
+		r.backSuffix = suffix
+		r.spaces = foundSpaces
+		f, err := NewFixFrom(r)
+		if err != nil {
+			return err
+		}
+		if err := rb.confirm(f); err != nil {
+			return err
+		}
+		log.WithField("suffix", suffix).WithField("spaces", foundSpaces).Info("Rollback origin data dirs.")
+		return f.moveBack()
+	}
+
+	return fmt.Errorf("no origin data dir with suffix %s is found in cluster", strings.Join(suffixes, ","))
+}
+
+// discoverOriginal returns the origin data dirs with the suffix in all metad and storaged, and the spaces in
+// sids whose origin dirs {data}/nebula/{space id}{suffix} are found in storaged, which are moved when some
+// spaces are restored. The agent could only check the existence since there is no listing, so the dirs of
+// the spaces not in sids, e.g. dropped by a restore failed before meta restored, could not be found.
+func (r *Restore) discoverOriginal(suffix string, sids []nebula.GraphSpaceID) ([]string, []nebula.GraphSpaceID, error) {
+	services := r.hosts.GetStorages()
+	services = append(services, r.hosts.GetMetas()...)
+
+	found := make([]string, 0)
+	spaceSet := make(map[nebula.GraphSpaceID]bool)
+	for _, s := range services {
+		agent, err := r.agentMgr.GetAgentFor(s.GetAddr())
+		if err != nil {
+			return nil, nil, fmt.Errorf("get agent for %s failed: %w", serviceName(s), err)
+		}
+		exist := func(p string) (bool, error) {
+			res, err := agent.ExistDir(&pb.ExistDirRequest{Path: p})
+			if err != nil {
+				return false, fmt.Errorf("check %s:%s exist failed: %w", serviceName(s), p, err)
+			}
+			return res.Exist, nil
+		}
+
+		paths := make([]string, 0, len(s.Dir.Data)+1)
+		for _, d := range s.Dir.Data {
+			paths = append(paths, filepath.Join(string(d), "nebula")+suffix)
+		}
+		if s.GetRole() == meta.HostRole_STORAGE {
+			paths = append(paths, filepath.Join(string(s.GetDir().GetRoot()), "cluster.id")+suffix)
+		}
+		for _, p := range paths {
+			ok, err := exist(p)
+			if err != nil {
+				return nil, nil, err
+			}
+			if ok {
+				found = append(found, fmt.Sprintf("%s:%s", serviceName(s), p))
+			}
+		}
+
+		if s.GetRole() != meta.HostRole_STORAGE {
+			continue
+		}
+		for _, d := range s.Dir.Data {
+			for _, sid := range sids {
+				p := filepath.Join(string(d), "nebula", strconv.Itoa(int(sid))) + suffix
+				ok, err := exist(p)
+				if err != nil {
+					return nil, nil, err
+				}
+				if ok {
+					spaceSet[sid] = true
+				}
+			}
+		}
+	}
+
+	spaces := make([]nebula.GraphSpaceID, 0, len(spaceSet))
+	for sid := range spaceSet {
+		spaces = append(spaces, sid)
+	}
+	sort.Slice(spaces, func(i, j int) bool { return spaces[i] < spaces[j] })
+	return found, spaces, nil
+}
+
+// journalSuffixes collects the suffixes recorded in the local journals in current dir,
+// the specified journal and the journals kept in backup dirs, sorted from the latest.
+func (rb *Rollback) journalSuffixes() []string {
+	files, _ := filepath.Glob("restore_*.journal")
+	if rb.cfg.JournalPath != "" {
+		files = append(files, rb.cfg.JournalPath)
+	}
+
+	journals := make([]*Journal, 0)
+	for _, f := range files {
+		j, err := ParseJournalFromFile(f)
+		if err != nil {
+			log.WithError(err).WithField("file", f).Debug("Parse local restore journal failed.")
+			continue
+		}
+		journals = append(journals, j)
+	}
+	journals = append(journals, rb.externalJournals()...)
+
+	set := make(map[string]bool)
+	suffixes := make([]string, 0)
+	for _, j := range journals {
+		if j.BackSuffix != "" && !set[j.BackSuffix] {
+			set[j.BackSuffix] = true
+			suffixes = append(suffixes, j.BackSuffix)
+		}
+	}
+
+	// suffix is _old_{unix timestamp}
+	ts := func(suffix string) int64 {
+		t, _ := strconv.ParseInt(strings.TrimPrefix(suffix, backupSuffixPrefix), 10, 64)
+		return t
+	}
+	sort.Slice(suffixes, func(i, j int) bool {
+		return ts(suffixes[i]) > ts(suffixes[j])
+	})
+	return suffixes
+}
+
+func (rb *Rollback) externalJournals() []*Journal {
+	if rb.sto == nil {
+		return nil
+	}
+	rootUri := rb.cfg.Backend.Uri()
+	names, err := rb.sto.ListDir(rb.ctx, rootUri)
+	if err != nil {
+		log.WithError(err).WithField("uri", rootUri).Warn("List backups failed.")
+		return nil
+	}
+
+	if err := utils.EnsureDir(utils.LocalTmpDir); err != nil {
+		log.WithError(err).Warn("Create local tmp dir failed.")
+		return nil
+	}
+	defer func() {
+		if err := utils.RemoveDir(utils.LocalTmpDir); err != nil {
+			log.WithError(err).Errorf("Remove tmp dir %s failed.", utils.LocalTmpDir)
+		}
+	}()
+
+	journals := make([]*Journal, 0)
+	for _, name := range names {
+		name = strings.Trim(name, "/")
+		if !utils.IsBackupName(name) {
+			continue
+		}
+
+		journalUri, _ := utils.UriJoin(rootUri, name, utils.RestoreJournalName)
+		tmpLocalPath := filepath.Join(utils.LocalTmpDir, utils.RestoreJournalName)
+		if err := rb.sto.Download(rb.ctx, tmpLocalPath, journalUri, false); err != nil {
+			// most backups have never been restored
+			log.WithField("uri", journalUri).Debug("No restore journal in backup.")
+			continue
+		}
+		j, err := ParseJournalFromFile(tmpLocalPath)
+		os.Remove(tmpLocalPath)
+		if err != nil {
+			log.WithError(err).WithField("uri", journalUri).Warn("Parse restore journal failed.")
+			continue
+		}
+		journals = append(journals, j)
+	}
+	return journals
+}
@@ -0,0 +1,138 @@
+package restore
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vesoft-inc/nebula-br/pkg/config"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+	"github.com/vesoft-inc/nebula-go/v3/nebula"
+	"github.com/vesoft-inc/nebula-go/v3/nebula/meta"
+)
+
+// filterSpaces returns a copy of the backup meta with only the spaces in ids
+func filterSpaces(bakMeta *meta.BackupMeta, ids []nebula.GraphSpaceID) *meta.BackupMeta {
+	selected := *bakMeta
+	selected.AllSpaces = false
+	selected.SpaceBackups = make(map[nebula.GraphSpaceID]*meta.SpaceBackupInfo)
+	for _, sid := range ids {
+		if sb, ok := bakMeta.GetSpaceBackups()[sid]; ok {
+			selected.SpaceBackups[sid] = sb
+		}
+	}
+	return &selected
+}
+
+// selectSpaces decides the spaces to restore by space dirs, and returns the backup meta with only them.
+// The whole data dirs are replaced as before if no space is specified.
+func (r *Restore) selectSpaces(bakMeta *meta.BackupMeta) (*meta.BackupMeta, error) {
+	if len(r.cfg.Spaces) == 0 {
+		return bakMeta, nil
+	}
+	// the meta files of a full-cluster backup have the users, roles, configs and the schema of all the spaces
+	if bakMeta.GetAllSpaces() && !r.cfg.Force {
+		return nil, fmt.Errorf("backup %s is of the entire cluster, restoring some spaces from it will revert the users, "+
+			"roles, configs and the schema of all the other spaces to the backup, specify --force if it is expected", r.backupName)
+	}
+
+	ids := make(map[string]nebula.GraphSpaceID)
+	for sid, sb := range bakMeta.GetSpaceBackups() {
+		ids[string(sb.GetSpace().GetSpaceName())] = sid
+	}
+	for _, name := range r.cfg.Spaces {
+		sid, ok := ids[name]
+		if !ok {
+			return nil, fmt.Errorf("space %s does not exist in backup %s", name, r.backupName)
+		}
+		r.spaces = append(r.spaces, sid)
+		delete(ids, name)
+	}
+	sort.Slice(r.spaces, func(i, j int) bool { return r.spaces[i] < r.spaces[j] })
+
+	unselected := make(map[nebula.GraphSpaceID]*meta.SpaceBackupInfo)
+	for _, sid := range ids {
+		unselected[sid] = bakMeta.GetSpaceBackups()[sid]
+	}
+	if err := r.checkUnselected(unselected); err != nil {
+		return nil, err
+	}
+	return filterSpaces(bakMeta, r.spaces), nil
+}
+
+// checkUnselected checks the spaces in backup but not selected to restore. The backup meta files
+// could not be split by space, so their meta will be restored too, they should be the same spaces
+// in cluster, otherwise the dropped ones would be brought back without data.
+func (r *Restore) checkUnselected(info map[nebula.GraphSpaceID]*meta.SpaceBackupInfo) error {
+	if len(info) == 0 {
+		return nil
+	}
+
+	existed, err := r.checkSpaces(info)
+	if err != nil {
+		return fmt.Errorf("check unselected spaces failed: %w", err)
+	}
+	set := make(map[string]bool)
+	for _, name := range existed {
+		set[name] = true
+	}
+	for _, sb := range info {
+		name := string(sb.GetSpace().GetSpaceName())
+		if !set[name] {
+			return fmt.Errorf("space %s in backup does not exist in cluster, its meta would be restored without data, please select it too", name)
+		}
+	}
+
+	sort.Strings(existed)
+	log.WithField("spaces", existed).
+		Warn("Meta of the unselected spaces in backup will be restored too, the schema changed after backup will be reverted.")
+	return nil
+}
+
+// dataPaths returns the storage data paths to be replaced in the data dir,
+// which are the space dirs if only some spaces are restored.
+func (r *Restore) dataPaths(dataDir []byte) []string {
+	opath := filepath.Join(string(dataDir), "nebula")
+	if len(r.spaces) == 0 {
+		return []string{opath}
+	}
+
+	paths := make([]string, 0, len(r.spaces))
+	for _, sid := range r.spaces {
+		paths = append(paths, filepath.Join(opath, strconv.Itoa(int(sid))))
+	}
+	return paths
+}
+
+// spaceDir is a dir in backup to download, prefix is like {addr}/data{0..n} or {addr}/data{0..n}/{spaceId},
+// and local is the dir relative to the target data path
+type spaceDir struct {
+	prefix string
+	local  string
+}
+
+// downloadDirs returns the dirs to download from the i-th data path of the storaged in backup,
+// it is the whole data path, or the dirs of the restored spaces which have data in it.
+func (r *Restore) downloadDirs(bakMeta *meta.BackupMeta, pair *config.StoragePair, i int) []*spaceDir {
+	prefix := path.Join(utils.StringifyAddr(pair.From), fmt.Sprintf("data%d", i))
+	if len(r.spaces) == 0 {
+		return []*spaceDir{{prefix: prefix, local: "nebula"}}
+	}
+
+	dirs := make([]*spaceDir, 0, len(r.spaces))
+	for _, sid := range r.spaces {
+		for _, hb := range bakMeta.GetSpaceBackups()[sid].GetHostBackups() {
+			// the space has data in data{i} only if it has the i-th checkpoint in backup
+			if utils.StringifyAddr(hb.GetHost()) != utils.StringifyAddr(pair.From) || i >= len(hb.GetCheckpoints()) {
+				continue
+			}
+			idStr := strconv.Itoa(int(sid))
+			dirs = append(dirs, &spaceDir{prefix: path.Join(prefix, idStr), local: path.Join("nebula", idStr)})
+		}
+	}
+	return dirs
+}
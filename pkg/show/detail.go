@@ -0,0 +1,188 @@
+package show
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+
+	"github.com/vesoft-inc/nebula-br/pkg/config"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+	"github.com/vesoft-inc/nebula-go/v3/nebula/meta"
+)
+
+type spaceDetail struct {
+	ID            int32  `json:"id" yaml:"id"`
+	Name          string `json:"name" yaml:"name"`
+	Partitions    int32  `json:"partitions" yaml:"partitions"`
+	ReplicaFactor int32  `json:"replica_factor" yaml:"replica_factor"`
+}
+
+type checkpointDetail struct {
+	SpaceID  int32  `json:"space_id" yaml:"space_id"`
+	DataPath string `json:"data_path" yaml:"data_path"` // checkpoint path in storaged when backup
+	Backup   string `json:"backup" yaml:"backup"`       // path relative to backup root in external storage
+	Parts    int    `json:"parts" yaml:"parts"`
+}
+
+type hostDetail struct {
+	Host        string              `json:"host" yaml:"host"`
+	Checkpoints []*checkpointDetail `json:"checkpoints" yaml:"checkpoints"`
+}
+
+// backupDetail is the detail of a single backup, TotalSize is -1 if there is no manifest
+type backupDetail struct {
+	BackupName string         `json:"name" yaml:"name"`
+	CreateTime string         `json:"create_time" yaml:"create_time"`
+	Full       bool           `json:"full" yaml:"full"`
+	AllSpaces  bool           `json:"all_spaces" yaml:"all_spaces"`
+	BaseBackup string         `json:"base_backup,omitempty" yaml:"base_backup,omitempty"`
+	EncryptKey string         `json:"encryption_key_id,omitempty" yaml:"encryption_key_id,omitempty"`
+	Spaces     []*spaceDetail `json:"spaces" yaml:"spaces"`
+	Hosts      []*hostDetail  `json:"hosts" yaml:"hosts"`
+	MetaFiles  []string       `json:"meta_files" yaml:"meta_files"`
+	TotalSize  int64          `json:"total_size" yaml:"total_size"`
+}
+
+func newBackupDetail(m *meta.BackupMeta) *backupDetail {
+	d := &backupDetail{
+		BackupName: string(m.GetBackupName()),
+		CreateTime: time.Unix(0, m.CreateTime*int64(time.Millisecond)).Format("2006-01-02 15:04:05"),
+		Full:       m.GetFull(),
+		AllSpaces:  m.GetAllSpaces(),
+		BaseBackup: string(m.GetBaseBackupName()),
+		TotalSize:  -1,
+	}
+
+	hosts := make(map[string]*hostDetail)
+	for sid, sb := range m.GetSpaceBackups() {
+		d.Spaces = append(d.Spaces, &spaceDetail{
+			ID:            int32(sid),
+			Name:          string(sb.GetSpace().GetSpaceName()),
+			Partitions:    sb.GetSpace().GetPartitionNum(),
+			ReplicaFactor: sb.GetSpace().GetReplicaFactor(),
+		})
+
+		for _, hb := range sb.GetHostBackups() {
+			addr := utils.StringifyAddr(hb.GetHost())
+			h, ok := hosts[addr]
+			if !ok {
+				h = &hostDetail{Host: addr}
+				hosts[addr] = h
+				d.Hosts = append(d.Hosts, h)
+			}
+			for i, cp := range hb.GetCheckpoints() {
+				h.Checkpoints = append(h.Checkpoints, &checkpointDetail{
+					SpaceID:  int32(sid),
+					DataPath: string(cp.GetDataPath()),
+					Backup:   fmt.Sprintf("data/%s/data%d/%d", addr, i, sid),
+					Parts:    len(cp.GetParts()),
+				})
+			}
+		}
+	}
+	for _, f := range m.GetMetaFiles() {
+		d.MetaFiles = append(d.MetaFiles, string(f))
+	}
+
+	sort.Slice(d.Spaces, func(i, j int) bool { return d.Spaces[i].ID < d.Spaces[j].ID })
+	sort.Slice(d.Hosts, func(i, j int) bool { return d.Hosts[i].Host < d.Hosts[j].Host })
+	for _, h := range d.Hosts {
+		cps := h.Checkpoints
+		sort.Slice(cps, func(i, j int) bool { return cps[i].Backup < cps[j].Backup })
+	}
+	sort.Strings(d.MetaFiles)
+	return d
+}
+
+func (d *backupDetail) print(w io.Writer) {
+	size := "unknown, no manifest"
+	if d.TotalSize >= 0 {
+		size = strconv.FormatInt(d.TotalSize, 10)
+	}
+	fmt.Fprintf(w, "Name:        %s\n", d.BackupName)
+	fmt.Fprintf(w, "Create time: %s\n", d.CreateTime)
+	fmt.Fprintf(w, "Full:        %t\n", d.Full)
+	fmt.Fprintf(w, "All spaces:  %t\n", d.AllSpaces)
+	if d.BaseBackup != "" {
+		fmt.Fprintf(w, "Base backup: %s\n", d.BaseBackup)
+	}
+	if d.EncryptKey != "" {
+		fmt.Fprintf(w, "Encrypted:   by key %s\n", d.EncryptKey)
+	}
+	fmt.Fprintf(w, "Total size:  %s\n", size)
+
+	fmt.Fprintln(w, "\nSpaces:")
+	tw := tablewriter.NewWriter(w)
+	tw.SetHeader([]string{"id", "name", "partitions", "replica_factor"})
+	for _, s := range d.Spaces {
+		tw.Append([]string{
+			strconv.Itoa(int(s.ID)),
+			s.Name,
+			strconv.Itoa(int(s.Partitions)),
+			strconv.Itoa(int(s.ReplicaFactor)),
+		})
+	}
+	tw.Render()
+
+	fmt.Fprintln(w, "\nStorage hosts:")
+	tw = tablewriter.NewWriter(w)
+	tw.SetHeader([]string{"host", "space_id", "parts", "data_path", "backup_path"})
+	for _, h := range d.Hosts {
+		for _, cp := range h.Checkpoints {
+			tw.Append([]string{h.Host, strconv.Itoa(int(cp.SpaceID)), strconv.Itoa(cp.Parts), cp.DataPath, cp.Backup})
+		}
+	}
+	tw.Render()
+
+	fmt.Fprintln(w, "\nMeta files:")
+	for _, f := range d.MetaFiles {
+		fmt.Fprintf(w, "  %s\n", f)
+	}
+}
+
+func (s *Show) showDetail(w io.Writer) error {
+	logger := log.WithField("backup", s.cfg.BackupName)
+	rootUri := s.cfg.Backend.Uri()
+
+	m, keyID, err := utils.DownloadMetaFileWithKeyID(s.ctx, s.sto, rootUri, s.cfg.BackupName, s.cfg.EncryptionKey)
+	if err != nil {
+		return fmt.Errorf("get backup meta file failed: %w", err)
+	}
+	d := newBackupDetail(m)
+	d.EncryptKey = keyID
+
+	// only the manifest records file sizes, since the storage could not stat files
+	manifest, err := utils.DownloadManifest(s.ctx, s.sto, rootUri, s.cfg.BackupName)
+	if err != nil {
+		logger.WithError(err).Info("Get backup manifest failed, total size is unknown.")
+	} else {
+		d.TotalSize = 0
+		for _, f := range manifest.Files {
+			d.TotalSize += f.Size
+		}
+	}
+
+	switch s.cfg.Output {
+	case config.OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	case config.OutputYAML:
+		data, err := yaml.Marshal(d)
+		if err != nil {
+			return fmt.Errorf("marshal backup detail to yaml failed: %w", err)
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	d.print(w)
+	return nil
+}
@@ -2,7 +2,10 @@ package show
 
 import (
 	"context"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -13,6 +16,7 @@ import (
 	_ "github.com/facebook/fbthrift/thrift/lib/go/thrift"
 	"github.com/olekukonko/tablewriter"
 	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
 
 	"github.com/vesoft-inc/nebula-agent/pkg/storage"
 	"github.com/vesoft-inc/nebula-br/pkg/config"
@@ -27,40 +31,46 @@ type Show struct {
 	backupNames []string
 }
 
+const (
+	statusOK     = "ok"
+	statusBroken = "broken"
+	// the backup is encrypted, but the key is not specified or not the right one
+	statusEncrypted = "encrypted"
+)
+
 type backupInfo struct {
-	BackupName string   `json:"name"`
-	CreateTime string   `json:"create_time"`
-	Spaces     []string `json:"spaces"`
-	Full       bool     `json:"full"`
-	AllSpaces  bool     `json:"all_spaces"`
+	BackupName string   `json:"name" yaml:"name"`
+	Status     string   `json:"status" yaml:"status"`
+	CreateTime string   `json:"create_time" yaml:"create_time"`
+	Spaces     []string `json:"spaces" yaml:"spaces"`
+	Full       bool     `json:"full" yaml:"full"`
+	AllSpaces  bool     `json:"all_spaces" yaml:"all_spaces"`
 }
 
 func (b *backupInfo) StringTable() []string {
-	brokenInfo := []string{"", "backup is broken", "N/A", "N/A", "N/A"}
-	if b == nil {
-		return brokenInfo
+	if b.Status != statusOK {
+		return []string{b.BackupName, b.Status, "N/A", "N/A", "N/A", "N/A"}
 	}
 
-	table := brokenInfo
-	table[0] = b.BackupName
-
-	if b.CreateTime == "" {
-		return table
+	return []string{
+		b.BackupName,
+		b.Status,
+		b.CreateTime,
+		strings.Join(b.Spaces, ","),
+		strconv.FormatBool(b.Full),
+		strconv.FormatBool(b.AllSpaces),
 	}
-	table[1] = b.CreateTime
+}
 
-	if len(b.Spaces) == 0 {
-		return table
+// Record returns the csv record, fields unknown for broken backup are left empty
+func (b *backupInfo) Record() []string {
+	if b.Status != statusOK {
+		return []string{b.BackupName, b.Status, "", "", "", ""}
 	}
-	table[2] = strings.Join(b.Spaces, ",")
-
-	table[3] = strconv.FormatBool(b.Full)
-	table[4] = strconv.FormatBool(b.AllSpaces)
-
-	return table
+	return b.StringTable()
 }
 
-var tableHeader = []string{"name", "create_time", "spaces", "full_backup", "all_spaces"}
+var tableHeader = []string{"name", "status", "create_time", "spaces", "full_backup", "all_spaces"}
 
 func NewShow(ctx context.Context, cfg *config.ShowConfig) (*Show, error) {
 	s, err := storage.New(cfg.Backend)
@@ -113,10 +123,15 @@ func (s *Show) parseMetaFiles(metaPaths map[string]string) ([]*backupInfo, error
 	var infoList []*backupInfo
 	for name, path := range metaPaths {
 		log.WithField("meta path", path).Debug("Start parse meta file.")
+		if _, err := utils.DecryptFile(s.cfg.EncryptionKey, path); err != nil {
+			log.WithError(err).WithField("meta path", path).Error("Decrypt meta file failed.")
+			infoList = append(infoList, &backupInfo{BackupName: name, Status: statusEncrypted})
+			continue
+		}
 		m, err := utils.ParseMetaFromFile(path)
 		if err != nil || m == nil {
 			log.WithError(err).WithField("meta path", path).Error("Parse meta file failed.")
-			infoList = append(infoList, &backupInfo{BackupName: name})
+			infoList = append(infoList, &backupInfo{BackupName: name, Status: statusBroken})
 			continue
 		}
 
@@ -131,6 +146,7 @@ func (s *Show) parseMetaFiles(metaPaths map[string]string) ([]*backupInfo, error
 
 		info := &backupInfo{
 			BackupName: string(m.BackupName),
+			Status:     statusOK,
 			CreateTime: time.Unix(0, m.CreateTime*int64(time.Millisecond)).Format("2006-01-02 15:04:05"),
 			Spaces:     spaces,
 			Full:       m.Full,
@@ -143,19 +159,42 @@ func (s *Show) parseMetaFiles(metaPaths map[string]string) ([]*backupInfo, error
 	return infoList, nil
 }
 
-func (s *Show) showBackupInfo(infoList []*backupInfo) {
+func (s *Show) showBackupInfo(w io.Writer, infoList []*backupInfo) error {
 	sort.Slice(infoList, func(i, j int) bool {
 		return strings.Compare(infoList[i].BackupName, infoList[j].BackupName) < 0
 	})
+
+	switch s.cfg.Output {
+	case config.OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(infoList)
+	case config.OutputYAML:
+		data, err := yaml.Marshal(infoList)
+		if err != nil {
+			return fmt.Errorf("marshal backup info to yaml failed: %w", err)
+		}
+		_, err = w.Write(data)
+		return err
+	case config.OutputCSV:
+		cw := csv.NewWriter(w)
+		records := [][]string{tableHeader}
+		for _, info := range infoList {
+			records = append(records, info.Record())
+		}
+		return cw.WriteAll(records)
+	}
+
 	asciiTable := make([][]string, 0)
 	for _, info := range infoList {
 		asciiTable = append(asciiTable, info.StringTable())
 	}
 
-	tw := tablewriter.NewWriter(os.Stdout)
+	tw := tablewriter.NewWriter(w)
 	tw.SetHeader(tableHeader)
 	tw.AppendBulk(asciiTable)
 	tw.Render()
+	return nil
 }
 
 func (s *Show) Show() error {
@@ -170,6 +209,11 @@ func (s *Show) Show() error {
 		}
 	}()
 
+	if s.cfg.BackupName != "" {
+		logger.WithField("backup", s.cfg.BackupName).Debug("Start show backup detail.")
+		return s.showDetail(os.Stdout)
+	}
+
 	logger.Debug("Start download backup meta files.")
 	files, err := s.downloadMetaFiles()
 	if err != nil {
@@ -183,6 +227,5 @@ func (s *Show) Show() error {
 	}
 
 	logger.Debug("Start show meta info.")
-	s.showBackupInfo(infoList)
-	return nil
+	return s.showBackupInfo(os.Stdout, infoList)
 }
@@ -0,0 +1,225 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+const (
+	// EncryptionKeyEnv is the env to specify the encryption key if the key file is not specified
+	EncryptionKeyEnv = "BR_ENCRYPTION_KEY"
+
+	encryptionMagic = "NEBULA-BR-ENCRYPTED\n"
+	keySize         = 32 // AES-256
+)
+
+// EncryptionKey is the master key specified by user, which only encrypts the data keys
+type EncryptionKey struct {
+	ID  string // recorded in encrypted files to tell which key is needed
+	key []byte
+}
+
+// NewEncryptionKey accepts 32 bytes raw key, or its hex or base64 encoding
+func NewEncryptionKey(raw []byte) (*EncryptionKey, error) {
+	key := raw
+	if len(key) != keySize {
+		text := string(bytes.TrimSpace(raw))
+		if k, err := hex.DecodeString(text); err == nil && len(k) == keySize {
+			key = k
+		} else if k, err := base64.StdEncoding.DecodeString(text); err == nil && len(k) == keySize {
+			key = k
+		} else {
+			return nil, fmt.Errorf("encryption key should be %d bytes, or its hex or base64 encoding", keySize)
+		}
+	}
+
+	sum := sha256.Sum256(key)
+	return &EncryptionKey{
+		ID:  hex.EncodeToString(sum[:8]),
+		key: key,
+	}, nil
+}
+
+// DataKey is generated for every backup to encrypt its files, and kept
+// in the encrypted files after wrapped by the master key.
+type DataKey struct {
+	keyID    string
+	key      []byte
+	wrapped  []byte
+	keyNonce []byte
+}
+
+type encryptionHeader struct {
+	KeyID      string `json:"key_id"`
+	WrappedKey []byte `json:"wrapped_key"`
+	KeyNonce   []byte `json:"key_nonce"`
+	Nonce      []byte `json:"nonce"`
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("generate random bytes failed: %w", err)
+	}
+	return b, nil
+}
+
+func (k *EncryptionKey) NewDataKey() (*DataKey, error) {
+	key, err := randomBytes(keySize)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(k.key)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomBytes(gcm.NonceSize())
+	if err != nil {
+		return nil, err
+	}
+
+	return &DataKey{
+		keyID:    k.ID,
+		key:      key,
+		wrapped:  gcm.Seal(nil, nonce, key, []byte(k.ID)),
+		keyNonce: nonce,
+	}, nil
+}
+
+func (dk *DataKey) KeyID() string {
+	return dk.keyID
+}
+
+// Encrypt encrypts data in format: magic | header json | '\n' | ciphertext
+func (dk *DataKey) Encrypt(data []byte) ([]byte, error) {
+	gcm, err := newGCM(dk.key)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomBytes(gcm.NonceSize())
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := json.Marshal(&encryptionHeader{
+		KeyID:      dk.keyID,
+		WrappedKey: dk.wrapped,
+		KeyNonce:   dk.keyNonce,
+		Nonce:      nonce,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal encryption header failed: %w", err)
+	}
+
+	buf := bytes.NewBufferString(encryptionMagic)
+	buf.Write(header)
+	buf.WriteByte('\n')
+	buf.Write(gcm.Seal(nil, nonce, data, header))
+	return buf.Bytes(), nil
+}
+
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(encryptionMagic))
+}
+
+func parseEncrypted(data []byte) (*encryptionHeader, []byte, []byte, error) {
+	rest := bytes.TrimPrefix(data, []byte(encryptionMagic))
+	idx := bytes.IndexByte(rest, '\n')
+	if idx < 0 {
+		return nil, nil, nil, fmt.Errorf("encryption header not found")
+	}
+
+	header := &encryptionHeader{}
+	if err := json.Unmarshal(rest[:idx], header); err != nil {
+		return nil, nil, nil, fmt.Errorf("unmarshal encryption header failed: %w", err)
+	}
+	return header, rest[:idx], rest[idx+1:], nil
+}
+
+// EncryptedKeyID returns the id of the master key which the data is encrypted by
+func EncryptedKeyID(data []byte) (string, error) {
+	header, _, _, err := parseEncrypted(data)
+	if err != nil {
+		return "", err
+	}
+	return header.KeyID, nil
+}
+
+func Decrypt(k *EncryptionKey, data []byte) ([]byte, error) {
+	header, rawHeader, ciphertext, err := parseEncrypted(data)
+	if err != nil {
+		return nil, err
+	}
+	if k == nil {
+		return nil, fmt.Errorf("data is encrypted by key %s, but no encryption key is specified", header.KeyID)
+	}
+	if header.KeyID != k.ID {
+		return nil, fmt.Errorf("data is encrypted by key %s, but the specified key is %s", header.KeyID, k.ID)
+	}
+
+	gcm, err := newGCM(k.key)
+	if err != nil {
+		return nil, err
+	}
+	key, err := gcm.Open(nil, header.KeyNonce, header.WrappedKey, []byte(header.KeyID))
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key failed: %w", err)
+	}
+
+	gcm, err = newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, header.Nonce, ciphertext, rawHeader)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt data failed: %w", err)
+	}
+	return plain, nil
+}
+
+// EncryptFile encrypts the file in place
+func EncryptFile(dk *DataKey, filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("read file %s failed: %w", filename, err)
+	}
+	encrypted, err := dk.Encrypt(data)
+	if err != nil {
+		return fmt.Errorf("encrypt %s failed: %w", filename, err)
+	}
+	return ioutil.WriteFile(filename, encrypted, 0644)
+}
+
+// DecryptFile decrypts the file in place and returns the id of key it encrypted by,
+// nothing is done and empty key id is returned if it is not encrypted.
+func DecryptFile(k *EncryptionKey, filename string) (string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("read file %s failed: %w", filename, err)
+	}
+	if !IsEncrypted(data) {
+		return "", nil
+	}
+
+	plain, err := Decrypt(k, data)
+	if err != nil {
+		return "", fmt.Errorf("decrypt %s failed: %w", filename, err)
+	}
+	return k.ID, ioutil.WriteFile(filename, plain, 0644)
+}
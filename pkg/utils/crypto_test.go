@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	assert := assert.New(t)
+
+	raw := bytes.Repeat([]byte{0x42}, 32)
+	key, err := NewEncryptionKey(raw)
+	assert.Nil(err)
+	hexKey, err := NewEncryptionKey([]byte(hex.EncodeToString(raw) + "\n"))
+	assert.Nil(err)
+	assert.Equal(key.ID, hexKey.ID)
+
+	dk, err := key.NewDataKey()
+	assert.Nil(err)
+	assert.Equal(key.ID, dk.KeyID())
+
+	plain := []byte("backup meta content")
+	encrypted, err := dk.Encrypt(plain)
+	assert.Nil(err)
+	assert.True(IsEncrypted(encrypted))
+	assert.False(IsEncrypted(plain))
+	assert.False(bytes.Contains(encrypted, plain))
+
+	keyID, err := EncryptedKeyID(encrypted)
+	assert.Nil(err)
+	assert.Equal(key.ID, keyID)
+
+	decrypted, err := Decrypt(key, encrypted)
+	assert.Nil(err)
+	assert.Equal(plain, decrypted)
+
+	// no key or wrong key
+	_, err = Decrypt(nil, encrypted)
+	assert.NotNil(err)
+	otherKey, err := NewEncryptionKey(bytes.Repeat([]byte{0x24}, 32))
+	assert.Nil(err)
+	_, err = Decrypt(otherKey, encrypted)
+	assert.NotNil(err)
+
+	// tampered data
+	encrypted[len(encrypted)-1] ^= 0xff
+	_, err = Decrypt(key, encrypted)
+	assert.NotNil(err)
+
+	_, err = NewEncryptionKey([]byte("too short"))
+	assert.NotNil(err)
+}
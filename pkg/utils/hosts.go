@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
@@ -173,6 +174,16 @@ func (h *NebulaHosts) GetRootDirs() map[string][]*HostDir {
 	return hostRoots
 }
 
+// Hosts returns the sorted hostnames or ips in cluster
+func (h *NebulaHosts) Hosts() []string {
+	hosts := make([]string, 0, len(h.hosts))
+	for host := range h.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
 func (h *NebulaHosts) GetHostServices() map[string][]*meta.ServiceInfo {
 	return h.hosts
 }
@@ -0,0 +1,199 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/vesoft-inc/nebula-agent/pkg/storage"
+)
+
+// ManifestFile is a file in backup, path is relative to the backup root dir
+type ManifestFile struct {
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum"` // sha256 in hex
+}
+
+// Manifest records all the files in a backup, which could be used to
+// verify the integrity of a backup without the cluster.
+type Manifest struct {
+	BackupName string          `json:"backup_name"`
+	Dirs       []string        `json:"dirs"` // dirs relative to backup root which are recorded
+	Files      []*ManifestFile `json:"files"`
+}
+
+func ManifestName(backupName string) string {
+	return fmt.Sprintf("%s.manifest", backupName)
+}
+
+func DumpManifestToFile(m *Manifest, filename string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal backup manifest failed: %w", err)
+	}
+
+	err = ioutil.WriteFile(filename, data, 0644)
+	if err != nil {
+		return fmt.Errorf("write backup manifest to %s failed: %w", filename, err)
+	}
+	return nil
+}
+
+func ParseManifestFromFile(filename string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read file %s failed: %w", filename, err)
+	}
+
+	m := &Manifest{}
+	err = json.Unmarshal(data, m)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal backup manifest from %s failed: %w", filename, err)
+	}
+	return m, nil
+}
+
+// DownloadManifest downloads and parses the manifest file of a backup
+func DownloadManifest(ctx context.Context, sto storage.ExternalStorage, rootUri, backupName string) (*Manifest, error) {
+	if err := EnsureDir(LocalTmpDir); err != nil {
+		return nil, err
+	}
+
+	manifestName := ManifestName(backupName)
+	manifestUri, _ := UriJoin(rootUri, backupName, manifestName)
+	tmpLocalPath := filepath.Join(LocalTmpDir, manifestName)
+	err := sto.Download(ctx, tmpLocalPath, manifestUri, false)
+	if err != nil {
+		return nil, fmt.Errorf("download %s to %s failed: %w", manifestUri, tmpLocalPath, err)
+	}
+	defer os.Remove(tmpLocalPath)
+
+	return ParseManifestFromFile(tmpLocalPath)
+}
+
+func checksumLocal(localPath string) (int64, string, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", fmt.Errorf("read %s failed: %w", localPath, err)
+	}
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ChecksumFile downloads a file in backup to local tmp path and computes its checksum,
+// file is relative to the backup root uri
+func ChecksumFile(ctx context.Context, sto storage.ExternalStorage, backupUri, file string) (*ManifestFile, error) {
+	if err := EnsureDir(LocalTmpDir); err != nil {
+		return nil, err
+	}
+
+	uri, _ := UriJoin(backupUri, file)
+	localPath := filepath.Join(LocalTmpDir, "checksum_"+path.Base(file))
+	err := sto.Download(ctx, localPath, uri, false)
+	if err != nil {
+		return nil, fmt.Errorf("download %s to %s failed: %w", uri, localPath, err)
+	}
+	defer os.Remove(localPath)
+
+	size, sum, err := checksumLocal(localPath)
+	if err != nil {
+		return nil, err
+	}
+	return &ManifestFile{Path: file, Size: size, Checksum: sum}, nil
+}
+
+// ChecksumDir downloads a dir in backup to local tmp path and computes checksum of
+// every file in it, dir is relative to the backup root uri
+func ChecksumDir(ctx context.Context, sto storage.ExternalStorage, backupUri, dir string) ([]*ManifestFile, error) {
+	localDir := filepath.Join(LocalTmpDir, "checksum")
+	if err := RemoveDir(localDir); err != nil {
+		return nil, err
+	}
+	if err := EnsureDir(localDir); err != nil {
+		return nil, err
+	}
+	defer RemoveDir(localDir)
+
+	uri, _ := UriJoin(backupUri, dir)
+	// avoid prefix matching other dirs
+	uri += "/"
+	err := sto.Download(ctx, localDir, uri, true)
+	if err != nil {
+		return nil, fmt.Errorf("download %s to %s failed: %w", uri, localDir, err)
+	}
+
+	var files []*ManifestFile
+	err = filepath.Walk(localDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(localDir, p)
+		if err != nil {
+			return err
+		}
+		size, sum, err := checksumLocal(p)
+		if err != nil {
+			return err
+		}
+		files = append(files, &ManifestFile{
+			Path:     path.Join(dir, filepath.ToSlash(rel)),
+			Size:     size,
+			Checksum: sum,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("compute checksum in %s failed: %w", localDir, err)
+	}
+	return files, nil
+}
+
+// CompareManifestFiles compares the actual files with the expected ones in manifest,
+// and returns the paths of missing, extra and corrupted files in order.
+func CompareManifestFiles(expected, actual []*ManifestFile) (missing, extra, corrupted []string) {
+	actualMap := make(map[string]*ManifestFile)
+	for _, f := range actual {
+		actualMap[f.Path] = f
+	}
+
+	for _, e := range expected {
+		a, ok := actualMap[e.Path]
+		if !ok {
+			missing = append(missing, e.Path)
+			continue
+		}
+		delete(actualMap, e.Path)
+
+		if a.Size != e.Size || a.Checksum != e.Checksum {
+			corrupted = append(corrupted, e.Path)
+		}
+	}
+
+	for p := range actualMap {
+		extra = append(extra, p)
+	}
+
+	sort.Strings(missing)
+	sort.Strings(extra)
+	sort.Strings(corrupted)
+	return
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareManifestFiles(t *testing.T) {
+	assert := assert.New(t)
+
+	expected := []*ManifestFile{
+		{Path: "meta/__edges__.sst", Size: 10, Checksum: "a"},
+		{Path: "meta/__tags__.sst", Size: 10, Checksum: "b"},
+		{Path: "data/127.0.0.1:9779/data0/1/data/000010.sst", Size: 100, Checksum: "c"},
+		{Path: "data/127.0.0.1:9779/data0/1/data/000011.sst", Size: 100, Checksum: "d"},
+	}
+	actual := []*ManifestFile{
+		{Path: "meta/__edges__.sst", Size: 10, Checksum: "a"},
+		{Path: "meta/__tags__.sst", Size: 11, Checksum: "b"},
+		{Path: "data/127.0.0.1:9779/data0/1/data/000010.sst", Size: 100, Checksum: "x"},
+		{Path: "data/127.0.0.1:9779/data0/1/data/000012.sst", Size: 100, Checksum: "e"},
+	}
+
+	missing, extra, corrupted := CompareManifestFiles(expected, actual)
+	assert.Equal([]string{"data/127.0.0.1:9779/data0/1/data/000011.sst"}, missing)
+	assert.Equal([]string{"data/127.0.0.1:9779/data0/1/data/000012.sst"}, extra)
+	assert.Equal([]string{"data/127.0.0.1:9779/data0/1/data/000010.sst", "meta/__tags__.sst"}, corrupted)
+
+	missing, extra, corrupted = CompareManifestFiles(expected, expected)
+	assert.Empty(missing)
+	assert.Empty(extra)
+	assert.Empty(corrupted)
+}
+
+func TestDumpParseManifest(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &Manifest{
+		BackupName: "BACKUP_TEST",
+		Dirs:       []string{"meta"},
+		Files: []*ManifestFile{
+			{Path: "meta/__edges__.sst", Size: 10, Checksum: "a"},
+		},
+	}
+
+	err := EnsureDir(LocalTmpDir)
+	assert.Nil(err, "Ensure local tmp dir failed", err)
+	defer func() {
+		err := RemoveDir(LocalTmpDir)
+		assert.Nil(err, "Remove local tmp dir failed", err)
+	}()
+
+	tmpPath := filepath.Join(LocalTmpDir, ManifestName(m.BackupName))
+	err = DumpManifestToFile(m, tmpPath)
+	assert.Nil(err, "Dump backup manifest to file failed", err)
+
+	m1, err := ParseManifestFromFile(tmpPath)
+	assert.Nil(err, "Parse backup manifest from file failed", err)
+	assert.Equal(m, m1)
+
+	size, sum, err := checksumLocal(tmpPath)
+	assert.Nil(err)
+	assert.NotZero(size)
+	assert.Len(sum, 64)
+}
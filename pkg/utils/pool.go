@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// MultiError aggregates errors from concurrent tasks
+type MultiError []error
+
+func (m MultiError) Error() string {
+	s := make([]string, 0, len(m))
+	for _, err := range m {
+		s = append(s, err.Error())
+	}
+	return fmt.Sprintf("%d errors occurred: %s", len(m), strings.Join(s, "; "))
+}
+
+// TaskError is the error of a task on a host, which keeps the host and path for reporting
+type TaskError struct {
+	Host string
+	Path string
+	Err  error
+}
+
+func (e *TaskError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// WorkerPool runs tasks concurrently with at most concurrency workers.
+// Once a task fails, the context passed to tasks will be canceled, and
+// the tasks not started yet will be skipped, unless ContinueOnError is set.
+type WorkerPool struct {
+	parent   context.Context
+	ctx      context.Context
+	cancel   context.CancelFunc
+	sem      chan struct{}
+	wg       sync.WaitGroup
+	failFast bool
+
+	mu   sync.Mutex
+	errs MultiError
+}
+
+func NewWorkerPool(ctx context.Context, concurrency int) *WorkerPool {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	child, cancel := context.WithCancel(ctx)
+	return &WorkerPool{
+		parent:   ctx,
+		ctx:      child,
+		cancel:   cancel,
+		sem:      make(chan struct{}, concurrency),
+		failFast: true,
+	}
+}
+
+// ContinueOnError lets the pool run all the tasks even if some of them fail,
+// so that all the errors could be collected
+func (p *WorkerPool) ContinueOnError() *WorkerPool {
+	p.failFast = false
+	return p
+}
+
+// Context returns the context which will be canceled when any task fails
+func (p *WorkerPool) Context() context.Context {
+	return p.ctx
+}
+
+// Go submits a task, it blocks until there is an idle worker
+func (p *WorkerPool) Go(name string, task func(ctx context.Context) error) {
+	select {
+	case p.sem <- struct{}{}:
+	case <-p.ctx.Done():
+		return
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer func() {
+			<-p.sem
+			p.wg.Done()
+		}()
+
+		if p.ctx.Err() != nil {
+			return
+		}
+		if err := task(p.ctx); err != nil {
+			p.mu.Lock()
+			p.errs = append(p.errs, fmt.Errorf("%s: %w", name, err))
+			p.mu.Unlock()
+			if p.failFast {
+				p.cancel()
+			}
+		}
+	}()
+}
+
+// Wait waits for all submitted tasks, and returns the aggregated errors if any
+func (p *WorkerPool) Wait() error {
+	p.wg.Wait()
+	p.cancel()
+
+	if len(p.errs) == 0 {
+		// tasks may be skipped because the parent is done
+		return p.parent.Err()
+	}
+	if len(p.errs) == 1 {
+		return p.errs[0]
+	}
+	return p.errs
+}
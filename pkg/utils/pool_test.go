@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerPool(t *testing.T) {
+	assert := assert.New(t)
+
+	// concurrency should be bounded
+	var running, maxRunning, finished int32
+	p := NewWorkerPool(context.Background(), 3)
+	for i := 0; i < 10; i++ {
+		p.Go(fmt.Sprintf("task%d", i), func(ctx context.Context) error {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			atomic.AddInt32(&finished, 1)
+			return nil
+		})
+	}
+	assert.Nil(p.Wait())
+	assert.Equal(int32(10), finished)
+	assert.LessOrEqual(maxRunning, int32(3))
+
+	// the context should be canceled once any task fails, and the tasks queued after it should never start
+	p = NewWorkerPool(context.Background(), 2)
+	var badStarted, goodStarted int32
+	for i := 0; i < 2; i++ {
+		p.Go(fmt.Sprintf("bad%d", i), func(ctx context.Context) error {
+			atomic.AddInt32(&badStarted, 1)
+			return errors.New("failed")
+		})
+	}
+	<-p.Context().Done()
+	for i := 0; i < 5; i++ {
+		p.Go(fmt.Sprintf("good%d", i), func(ctx context.Context) error {
+			atomic.AddInt32(&goodStarted, 1)
+			return nil
+		})
+	}
+	err := p.Wait()
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "failed")
+	assert.GreaterOrEqual(badStarted, int32(1))
+	assert.Equal(int32(0), goodStarted)
+
+	// all the errors should be collected if continue on error
+	p = NewWorkerPool(context.Background(), 2).ContinueOnError()
+	started := int32(0)
+	for i := 0; i < 5; i++ {
+		p.Go(fmt.Sprintf("bad%d", i), func(ctx context.Context) error {
+			atomic.AddInt32(&started, 1)
+			return errors.New("failed")
+		})
+	}
+	err = p.Wait()
+	var merr MultiError
+	assert.True(errors.As(err, &merr))
+	assert.Len(merr, 5)
+	assert.Equal(int32(5), started)
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var rateUnits = []struct {
+	suffix string
+	size   float64
+}{
+	// longer suffixes first
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+	{"B", 1},
+}
+
+// ParseRate parses rate like 200MB/s or 1.5GiB/s to bytes per second, units are
+// in 1024 multiples and "/s" is optional. Empty string or 0 means unlimited.
+func ParseRate(s string) (int64, error) {
+	text := strings.ToUpper(strings.TrimSpace(s))
+	text = strings.TrimSuffix(text, "/S")
+	if text == "" || text == "0" {
+		return 0, nil
+	}
+
+	unit := 1.0
+	for _, u := range rateUnits {
+		if strings.HasSuffix(text, u.suffix) {
+			text = strings.TrimSpace(strings.TrimSuffix(text, u.suffix))
+			unit = u.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(text, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid rate %s, should be like 200MB/s", s)
+	}
+	return int64(n * unit), nil
+}
+
+func FormatRate(rate int64) string {
+	if rate <= 0 {
+		return "unlimited"
+	}
+	return FormatSize(rate) + "/s"
+}
+
+// FormatSize formats bytes in 1024 multiples with at most 2 decimals, e.g. 1.5GB
+func FormatSize(size int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	s, i := float64(size), 0
+	for s >= 1024 && i < len(units)-1 {
+		s /= 1024
+		i++
+	}
+	text := strings.TrimRight(strings.TrimRight(strconv.FormatFloat(s, 'f', 2, 64), "0"), ".")
+	return text + units[i]
+}
+
+// RateLimiter paces transfers at the granularity of a whole transfer, the
+// bytes of a transfer are reserved before it starts, so the average rate of
+// all the transfers is limited, rather than the instantaneous rate.
+type RateLimiter struct {
+	rate int64 // bytes per second
+
+	mu   sync.Mutex
+	next time.Time // the time when the reserved bytes are all transferred
+}
+
+// NewRateLimiter returns nil if rate is not positive, which means unlimited
+func NewRateLimiter(rate int64) *RateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &RateLimiter{rate: rate}
+}
+
+func (l *RateLimiter) reserve(n int64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(time.Duration(float64(n) / float64(l.rate) * float64(time.Second)))
+	return start.Sub(now)
+}
+
+// Wait blocks until n bytes could be transferred, it returns immediately for nil limiter
+func (l *RateLimiter) Wait(ctx context.Context, n int64) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+
+	delay := l.reserve(n)
+	if delay <= 0 {
+		return nil
+	}
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRate(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := map[string]int64{
+		"":         0,
+		"0":        0,
+		"200MB/s":  200 << 20,
+		"200mb/s":  200 << 20,
+		"1.5GiB/s": 3 << 29,
+		"512K":     512 << 10,
+		"1024":     1024,
+		"100 B/s":  100,
+	}
+	for s, expected := range cases {
+		rate, err := ParseRate(s)
+		assert.Nil(err, s)
+		assert.Equal(expected, rate, s)
+	}
+
+	for _, s := range []string{"fast", "-1MB/s", "2 GB / s"} {
+		_, err := ParseRate(s)
+		assert.NotNil(err, s)
+	}
+
+	assert.Equal("200MB/s", FormatRate(200<<20))
+	assert.Equal("1.5GB/s", FormatRate(3<<29))
+	assert.Equal("unlimited", FormatRate(0))
+	assert.Equal("0B", FormatSize(0))
+	assert.Equal("1.33KB", FormatSize(1362))
+}
+
+func TestRateLimiter(t *testing.T) {
+	assert := assert.New(t)
+
+	var unlimited *RateLimiter
+	assert.Nil(NewRateLimiter(0))
+	assert.Nil(unlimited.Wait(context.Background(), 1<<30))
+
+	// the first transfer starts immediately, the second waits for the first one
+	l := NewRateLimiter(1000)
+	start := time.Now()
+	assert.Nil(l.Wait(context.Background(), 100))
+	assert.Less(int64(time.Since(start)), int64(50*time.Millisecond))
+	assert.Nil(l.Wait(context.Background(), 100))
+	assert.GreaterOrEqual(int64(time.Since(start)), int64(90*time.Millisecond))
+
+	// canceled while waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.NotNil(l.Wait(ctx, 100))
+}
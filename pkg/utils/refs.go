@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/vesoft-inc/nebula-agent/pkg/storage"
+)
+
+// BackupRefs records the sst files an incremental backup shares with
+// the backups it based on, instead of uploading them again.
+type BackupRefs struct {
+	Base  string   `json:"base"`
+	Chain []string `json:"chain"` // from the direct base to the full backup
+	// Files group the inherited files by the storage dir they belong to,
+	// {addr}/data{0..n}/{spaceId}/data -> file name -> backup holding the file
+	Files map[string]map[string]string `json:"files"`
+}
+
+func NewBackupRefs(base string) *BackupRefs {
+	return &BackupRefs{
+		Base:  base,
+		Chain: []string{base},
+		Files: make(map[string]map[string]string),
+	}
+}
+
+func (r *BackupRefs) Add(dir, file, owner string) {
+	if _, ok := r.Files[dir]; !ok {
+		r.Files[dir] = make(map[string]string)
+	}
+	r.Files[dir][file] = owner
+}
+
+func RefsName(backupName string) string {
+	return fmt.Sprintf("%s.refs", backupName)
+}
+
+func DumpRefsToFile(refs *BackupRefs, filename string) error {
+	data, err := json.Marshal(refs)
+	if err != nil {
+		return fmt.Errorf("marshal backup refs failed: %w", err)
+	}
+
+	err = ioutil.WriteFile(filename, data, 0644)
+	if err != nil {
+		return fmt.Errorf("write backup refs to %s failed: %w", filename, err)
+	}
+	return nil
+}
+
+func ParseRefsFromFile(filename string) (*BackupRefs, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read file %s failed: %w", filename, err)
+	}
+
+	refs := &BackupRefs{}
+	err = json.Unmarshal(data, refs)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal backup refs from %s failed: %w", filename, err)
+	}
+	return refs, nil
+}
+
+// DownloadRefs downloads and parses the refs file of an incremental backup
+func DownloadRefs(ctx context.Context, sto storage.ExternalStorage, rootUri, backupName string,
+	key *EncryptionKey) (*BackupRefs, error) {
+	if err := EnsureDir(LocalTmpDir); err != nil {
+		return nil, err
+	}
+
+	refsName := RefsName(backupName)
+	refsUri, _ := UriJoin(rootUri, backupName, refsName)
+	tmpLocalPath := filepath.Join(LocalTmpDir, refsName)
+	err := sto.Download(ctx, tmpLocalPath, refsUri, false)
+	if err != nil {
+		return nil, fmt.Errorf("download %s to %s failed: %w", refsUri, tmpLocalPath, err)
+	}
+	defer os.Remove(tmpLocalPath)
+
+	if _, err := DecryptFile(key, tmpLocalPath); err != nil {
+		return nil, err
+	}
+	return ParseRefsFromFile(tmpLocalPath)
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDumpParseRefs(t *testing.T) {
+	assert := assert.New(t)
+
+	refs := NewBackupRefs("BACKUP_BASE")
+	refs.Chain = append(refs.Chain, "BACKUP_FULL")
+	refs.Add("127.0.0.1:9779/data0/1/data", "000010.sst", "BACKUP_FULL")
+	refs.Add("127.0.0.1:9779/data0/1/data", "000012.sst", "BACKUP_BASE")
+	refs.Add("127.0.0.1:9779/data1/1/data", "000011.sst", "BACKUP_FULL")
+
+	err := EnsureDir(LocalTmpDir)
+	assert.Nil(err, "Ensure local tmp dir failed", err)
+	defer func() {
+		err := RemoveDir(LocalTmpDir)
+		assert.Nil(err, "Remove local tmp dir failed", err)
+	}()
+
+	tmpPath := filepath.Join(LocalTmpDir, RefsName("BACKUP_INCR"))
+	err = DumpRefsToFile(refs, tmpPath)
+	assert.Nil(err, "Dump backup refs to file failed", err)
+
+	refs1, err := ParseRefsFromFile(tmpPath)
+	assert.Nil(err, "Parse backup refs from file failed", err)
+	assert.Equal(refs, refs1, "Backup refs are not consistent after dump and parse")
+	assert.Len(refs1.Files["127.0.0.1:9779/data0/1/data"], 2)
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetentionBackup is the backup info used to apply retention policy
+type RetentionBackup struct {
+	Name       string
+	CreateTime time.Time
+	Base       string // base backup name, empty for full backup
+}
+
+// RetentionPolicy decides which backups to keep, a backup is kept if any rule matches
+type RetentionPolicy struct {
+	KeepLast    int
+	KeepWithin  time.Duration
+	KeepDaily   int
+	KeepWeekly  int
+	KeepMonthly int
+}
+
+func (p *RetentionPolicy) Empty() bool {
+	return p.KeepLast <= 0 && p.KeepWithin <= 0 && p.KeepDaily <= 0 && p.KeepWeekly <= 0 && p.KeepMonthly <= 0
+}
+
+// Apply returns the reasons to keep every backup, the backups without reasons
+// should be deleted. The base chains of kept incremental backups are always kept.
+func (p *RetentionPolicy) Apply(backups []*RetentionBackup, now time.Time) map[string][]string {
+	sorted := make([]*RetentionBackup, len(backups))
+	copy(sorted, backups)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].CreateTime.After(sorted[j].CreateTime)
+	})
+
+	reasons := make(map[string][]string)
+	keep := func(name, reason string) {
+		reasons[name] = append(reasons[name], reason)
+	}
+
+	for i, b := range sorted {
+		if i < p.KeepLast {
+			keep(b.Name, "last")
+		}
+		if p.KeepWithin > 0 && now.Sub(b.CreateTime) <= p.KeepWithin {
+			keep(b.Name, "within "+FormatRetentionDuration(p.KeepWithin))
+		}
+	}
+
+	// keep the latest backup in each of the latest n periods
+	buckets := []struct {
+		reason string
+		n      int
+		period func(t time.Time) string
+	}{
+		{"daily", p.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{"weekly", p.KeepWeekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-%02d", year, week)
+		}},
+		{"monthly", p.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") }},
+	}
+	for _, bucket := range buckets {
+		last, count := "", 0
+		for _, b := range sorted {
+			if count >= bucket.n {
+				break
+			}
+			period := bucket.period(b.CreateTime)
+			if period != last {
+				keep(b.Name, bucket.reason)
+				last = period
+				count++
+			}
+		}
+	}
+
+	// incremental backup could not be restored without its base chain
+	byName := make(map[string]*RetentionBackup)
+	kept := make([]*RetentionBackup, 0, len(reasons))
+	for _, b := range sorted {
+		byName[b.Name] = b
+		if len(reasons[b.Name]) != 0 {
+			kept = append(kept, b)
+		}
+	}
+	for _, b := range kept {
+		for base := b.Base; base != ""; {
+			keep(base, "base of "+b.Name)
+			bb, ok := byName[base]
+			if !ok {
+				break
+			}
+			base = bb.Base
+		}
+	}
+	return reasons
+}
+
+// ParseRetentionDuration parses duration like 30d, 2w besides the formats of time.ParseDuration
+func ParseRetentionDuration(s string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+	for suffix, unit := range units {
+		if strings.HasSuffix(s, suffix) {
+			n, err := strconv.Atoi(strings.TrimSuffix(s, suffix))
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %s: %w", s, err)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+func FormatRetentionDuration(d time.Duration) string {
+	day := 24 * time.Hour
+	if d%day == 0 {
+		return fmt.Sprintf("%dd", d/day)
+	}
+	return d.String()
+}
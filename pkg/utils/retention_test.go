@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetentionPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2022, 3, 31, 12, 0, 0, 0, time.Local)
+	day := 24 * time.Hour
+	backups := []*RetentionBackup{
+		{Name: "BACKUP_0", CreateTime: now.Add(-60 * day)},
+		{Name: "BACKUP_1", CreateTime: now.Add(-40 * day)},
+		{Name: "BACKUP_2", CreateTime: now.Add(-10 * day)},
+		{Name: "BACKUP_3", CreateTime: now.Add(-2 * day)},
+		{Name: "BACKUP_4", CreateTime: now.Add(-2*day + time.Hour)},
+		{Name: "BACKUP_5", CreateTime: now.Add(-time.Hour)},
+	}
+
+	p := &RetentionPolicy{KeepLast: 2}
+	reasons := p.Apply(backups, now)
+	assert.Len(reasons, 2)
+	assert.Equal([]string{"last"}, reasons["BACKUP_5"])
+	assert.Equal([]string{"last"}, reasons["BACKUP_4"])
+
+	p = &RetentionPolicy{KeepWithin: 30 * day}
+	reasons = p.Apply(backups, now)
+	assert.Len(reasons, 4)
+	assert.Equal([]string{"within 30d"}, reasons["BACKUP_2"])
+
+	// BACKUP_3 and BACKUP_4 are in the same day, only the latest one is kept
+	p = &RetentionPolicy{KeepDaily: 3}
+	reasons = p.Apply(backups, now)
+	assert.Len(reasons, 3)
+	assert.Contains(reasons, "BACKUP_5")
+	assert.Contains(reasons, "BACKUP_4")
+	assert.NotContains(reasons, "BACKUP_3")
+	assert.Contains(reasons, "BACKUP_2")
+
+	p = &RetentionPolicy{KeepMonthly: 3}
+	reasons = p.Apply(backups, now)
+	assert.Len(reasons, 3)
+	assert.Contains(reasons, "BACKUP_5")
+	assert.Contains(reasons, "BACKUP_1")
+	assert.Contains(reasons, "BACKUP_0")
+
+	assert.True((&RetentionPolicy{}).Empty())
+	assert.False(p.Empty())
+}
+
+func TestRetentionKeepBaseChain(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now()
+	backups := []*RetentionBackup{
+		{Name: "BACKUP_FULL", CreateTime: now.Add(-3 * time.Hour)},
+		{Name: "BACKUP_INCR1", CreateTime: now.Add(-2 * time.Hour), Base: "BACKUP_FULL"},
+		{Name: "BACKUP_INCR2", CreateTime: now.Add(-1 * time.Hour), Base: "BACKUP_INCR1"},
+	}
+
+	p := &RetentionPolicy{KeepLast: 1}
+	reasons := p.Apply(backups, now)
+	assert.Len(reasons, 3)
+	assert.Equal([]string{"last"}, reasons["BACKUP_INCR2"])
+	assert.Equal([]string{"base of BACKUP_INCR2"}, reasons["BACKUP_INCR1"])
+	assert.Equal([]string{"base of BACKUP_INCR2"}, reasons["BACKUP_FULL"])
+}
+
+func TestParseRetentionDuration(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := map[string]time.Duration{
+		"30d": 30 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"12h": 12 * time.Hour,
+	}
+	for s, expected := range cases {
+		d, err := ParseRetentionDuration(s)
+		assert.Nil(err)
+		assert.Equal(expected, d)
+	}
+	assert.Equal("30d", FormatRetentionDuration(30*24*time.Hour))
+	assert.Equal("12h0m0s", FormatRetentionDuration(12*time.Hour))
+
+	_, err := ParseRetentionDuration("xd")
+	assert.NotNil(err)
+}
@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryPolicy is shared by the meta RPCs and agent calls
+type RetryPolicy struct {
+	MaxAttempts int           // including the first attempt
+	BaseBackoff time.Duration // backoff before the second attempt, doubled for each later one
+	MaxBackoff  time.Duration
+	Jitter      float64 // randomize the backoff in [1-jitter, 1+jitter]
+
+	Timeout         time.Duration // timeout of a call, 0 means no timeout
+	TransferTimeout time.Duration // timeout of uploading or downloading files, 0 means no timeout
+}
+
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		BaseBackoff: time.Second,
+		MaxBackoff:  30 * time.Second,
+		Jitter:      0.2,
+		Timeout:     120 * time.Second,
+	}
+}
+
+// Backoff returns the time to wait before the attempt, which starts from 2
+func (p *RetryPolicy) Backoff(attempt int) time.Duration {
+	d := p.BaseBackoff
+	for i := 2; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		d = time.Duration(float64(d) * (1 + p.Jitter*(2*rand.Float64()-1)))
+	}
+	return d
+}
+
+// fatalError is not retried
+type fatalError struct {
+	err error
+}
+
+func (e *fatalError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fatalError) Unwrap() error {
+	return e.err
+}
+
+// Fatal marks the error not retryable, e.g. an error code in response of meta
+func Fatal(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &fatalError{err: err}
+}
+
+// Retryable tells whether the call should be retried after the error, errors are
+// retryable by default, since most of them are from the network or unavailable services
+func Retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var fatal *fatalError
+	if errors.As(err, &fatal) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var s interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &s) {
+		switch s.GRPCStatus().Code() {
+		case codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.PermissionDenied,
+			codes.Unauthenticated, codes.FailedPrecondition, codes.OutOfRange, codes.Unimplemented,
+			codes.Canceled:
+			return false
+		}
+	}
+	return true
+}
+
+// Do calls f until it succeeds, a fatal error occurs or the attempts run out
+func (p *RetryPolicy) Do(ctx context.Context, name string, f func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = f(); err == nil {
+			return nil
+		}
+		if !Retryable(err) {
+			return err
+		}
+		if attempt >= p.MaxAttempts {
+			if attempt == 1 {
+				return err
+			}
+			return fmt.Errorf("%s failed after %d attempts: %w", name, attempt, err)
+		}
+
+		backoff := p.Backoff(attempt + 1)
+		log.WithError(err).WithField("attempt", attempt).WithField("backoff", backoff).
+			Warnf("%s failed, will retry.", name)
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	assert := assert.New(t)
+
+	p := &RetryPolicy{BaseBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(time.Second, p.Backoff(2))
+	assert.Equal(2*time.Second, p.Backoff(3))
+	assert.Equal(4*time.Second, p.Backoff(4))
+	assert.Equal(5*time.Second, p.Backoff(5))
+	assert.Equal(5*time.Second, p.Backoff(100))
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := p.Backoff(3)
+		assert.True(d >= time.Second && d <= 3*time.Second, d)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.False(Retryable(nil))
+	assert.True(Retryable(fmt.Errorf("connection refused")))
+	assert.False(Retryable(Fatal(fmt.Errorf("space not found"))))
+	assert.False(Retryable(fmt.Errorf("wrapped: %w", Fatal(fmt.Errorf("space not found")))))
+	assert.False(Retryable(fmt.Errorf("wrapped: %w", context.Canceled)))
+	assert.True(Retryable(status.Error(codes.Unavailable, "agent is down")))
+	assert.False(Retryable(fmt.Errorf("wrapped: %w", status.Error(codes.NotFound, "no such dir"))))
+}
+
+func TestRetryDo(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	p := &RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond}
+
+	calls := 0
+	err := p.Do(ctx, "succeed at last", func() error {
+		calls++
+		if calls < 3 {
+			return fmt.Errorf("try again")
+		}
+		return nil
+	})
+	assert.Nil(err)
+	assert.Equal(3, calls)
+
+	calls = 0
+	err = p.Do(ctx, "always fail", func() error {
+		calls++
+		return fmt.Errorf("try again")
+	})
+	assert.NotNil(err)
+	assert.Equal(3, calls)
+
+	calls = 0
+	err = p.Do(ctx, "fatal", func() error {
+		calls++
+		return Fatal(fmt.Errorf("no way"))
+	})
+	assert.NotNil(err)
+	assert.Equal(1, calls)
+}
@@ -31,6 +31,14 @@ func ParseAddr(addrStr string) (*nebula.HostAddr, error) {
 	return &nebula.HostAddr{Host: ipAddr[0], Port: nebula.Port(port)}, nil
 }
 
+// LessAddr orders the addresses by host and then port
+func LessAddr(a, b *nebula.HostAddr) bool {
+	if a.GetHost() != b.GetHost() {
+		return a.GetHost() < b.GetHost()
+	}
+	return a.GetPort() < b.GetPort()
+}
+
 func StringifyBackup(b *meta.BackupMeta) string {
 	m := map[string]string{
 		"backup name":  string(b.GetBackupName()),
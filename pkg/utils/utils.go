@@ -1,15 +1,18 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/facebook/fbthrift/thrift/lib/go/thrift"
 
 	pb "github.com/vesoft-inc/nebula-agent/pkg/proto"
+	"github.com/vesoft-inc/nebula-agent/pkg/storage"
 	"github.com/vesoft-inc/nebula-go/v3/nebula/meta"
 )
 
@@ -63,8 +66,43 @@ func ParseMetaFromFile(filename string) (*meta.BackupMeta, error) {
 	return m, nil
 }
 
+// DownloadMetaFile downloads and parses the backup meta file of the given backup,
+// key is used to decrypt the meta file if it is encrypted.
+func DownloadMetaFile(ctx context.Context, sto storage.ExternalStorage, rootUri, backupName string,
+	key *EncryptionKey) (*meta.BackupMeta, error) {
+	m, _, err := DownloadMetaFileWithKeyID(ctx, sto, rootUri, backupName, key)
+	return m, err
+}
+
+// DownloadMetaFileWithKeyID also returns the id of the key which the meta file is encrypted by,
+// empty if it is not encrypted.
+func DownloadMetaFileWithKeyID(ctx context.Context, sto storage.ExternalStorage, rootUri, backupName string,
+	key *EncryptionKey) (*meta.BackupMeta, string, error) {
+	if err := EnsureDir(LocalTmpDir); err != nil {
+		return nil, "", err
+	}
+
+	metaName := fmt.Sprintf("%s.meta", backupName)
+	metaUri, _ := UriJoin(rootUri, backupName, metaName)
+	tmpLocalPath := filepath.Join(LocalTmpDir, metaName)
+	err := sto.Download(ctx, tmpLocalPath, metaUri, false)
+	if err != nil {
+		return nil, "", fmt.Errorf("download %s to %s failed: %w", metaUri, tmpLocalPath, err)
+	}
+	defer os.Remove(tmpLocalPath)
+
+	keyID, err := DecryptFile(key, tmpLocalPath)
+	if err != nil {
+		return nil, "", err
+	}
+	m, err := ParseMetaFromFile(tmpLocalPath)
+	return m, keyID, err
+}
+
 const (
 	LocalTmpDir = "/tmp/nebula-br"
+	// RestoreJournalName is the restore journal file kept in the backup dir
+	RestoreJournalName = "restore.journal"
 )
 
 func EnsureDir(dir string) error {
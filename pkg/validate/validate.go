@@ -0,0 +1,281 @@
+package validate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vesoft-inc/nebula-agent/pkg/storage"
+	"github.com/vesoft-inc/nebula-br/pkg/clients"
+	"github.com/vesoft-inc/nebula-br/pkg/config"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+	"github.com/vesoft-inc/nebula-go/v3/nebula"
+	"github.com/vesoft-inc/nebula-go/v3/nebula/meta"
+)
+
+// Discrepancy is an item of a space in cluster which is not consistent with the backup
+type Discrepancy struct {
+	Space    string
+	Item     string
+	Expected string
+	Actual   string
+}
+
+func (d *Discrepancy) Error() string {
+	return fmt.Sprintf("%s of space %s is %s, but %s in backup", d.Item, d.Space, d.Actual, d.Expected)
+}
+
+// SpaceReader reads the spaces in cluster, it is clients.NebulaMeta
+type SpaceReader interface {
+	GetSpace(space []byte) (*meta.GetSpaceResp, error)
+	ListSpaces() ([]*meta.IdName, error)
+	GetPartsAlloc(spaceID nebula.GraphSpaceID) (map[nebula.PartitionID][]*nebula.HostAddr, error)
+}
+
+// Compare compares the spaces in cluster with the ones in backup meta, storageMap maps
+// the storaged addresses in backup to the ones in cluster.
+func Compare(m SpaceReader, backup *meta.BackupMeta, storageMap map[string]string) ([]*Discrepancy, error) {
+	var ds []*Discrepancy
+	names := make(map[string]bool)
+	for sid, sb := range backup.GetSpaceBackups() {
+		name := string(sb.GetSpace().GetSpaceName())
+		names[name] = true
+
+		spaceDs, err := compareSpace(m, sid, sb, storageMap)
+		if err != nil {
+			return nil, fmt.Errorf("validate space %s failed: %w", name, err)
+		}
+		ds = append(ds, spaceDs...)
+	}
+
+	// the spaces created after backup should have been removed by restoring all spaces
+	if backup.GetAllSpaces() {
+		spaces, err := m.ListSpaces()
+		if err != nil {
+			return nil, fmt.Errorf("list spaces failed: %w", err)
+		}
+		for _, s := range spaces {
+			if !names[string(s.GetName())] {
+				ds = append(ds, &Discrepancy{Space: string(s.GetName()), Item: "existence", Expected: "not existed", Actual: "existed"})
+			}
+		}
+	}
+
+	sort.SliceStable(ds, func(i, j int) bool {
+		return ds[i].Space < ds[j].Space
+	})
+	return ds, nil
+}
+
+func compareSpace(m SpaceReader, sid nebula.GraphSpaceID, sb *meta.SpaceBackupInfo,
+	storageMap map[string]string) ([]*Discrepancy, error) {
+	desc := sb.GetSpace()
+	name := string(desc.GetSpaceName())
+	diff := func(item string, expected, actual interface{}) *Discrepancy {
+		return &Discrepancy{Space: name, Item: item, Expected: fmt.Sprint(expected), Actual: fmt.Sprint(actual)}
+	}
+
+	resp, err := m.GetSpace(desc.GetSpaceName())
+	if err != nil {
+		return nil, err
+	}
+	if resp.GetCode() == nebula.ErrorCode_E_SPACE_NOT_FOUND {
+		return []*Discrepancy{diff("existence", "existed", "not existed")}, nil
+	}
+	if resp.GetCode() != nebula.ErrorCode_SUCCEEDED {
+		return nil, fmt.Errorf("call GetSpace failed: %s", resp.GetCode().String())
+	}
+
+	var ds []*Discrepancy
+	item := resp.GetItem()
+	if item.GetSpaceID() != sid {
+		ds = append(ds, diff("space id", sid, item.GetSpaceID()))
+	}
+	props := item.GetProperties()
+	if props.GetPartitionNum() != desc.GetPartitionNum() {
+		ds = append(ds, diff("partition num", desc.GetPartitionNum(), props.GetPartitionNum()))
+	}
+	if props.GetReplicaFactor() != desc.GetReplicaFactor() {
+		ds = append(ds, diff("replica factor", desc.GetReplicaFactor(), props.GetReplicaFactor()))
+	}
+
+	parts, err := m.GetPartsAlloc(item.GetSpaceID())
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) != int(desc.GetPartitionNum()) {
+		ds = append(ds, diff("allocated partitions", desc.GetPartitionNum(), len(parts)))
+	}
+
+	// the partitions could only be on the storaged which have the space data in backup
+	expected := make(map[string]bool)
+	for _, hb := range sb.GetHostBackups() {
+		addr := utils.StringifyAddr(hb.GetHost())
+		if to, ok := storageMap[addr]; ok {
+			addr = to
+		}
+		expected[addr] = true
+	}
+	actual := make(map[string]bool)
+	var badReplicas []string
+	for pid, hosts := range parts {
+		if len(hosts) != int(desc.GetReplicaFactor()) {
+			badReplicas = append(badReplicas, fmt.Sprintf("%d:%d", pid, len(hosts)))
+		}
+		for _, h := range hosts {
+			actual[utils.StringifyAddr(h)] = true
+		}
+	}
+	if len(badReplicas) != 0 {
+		sort.Strings(badReplicas)
+		ds = append(ds, diff("partition replicas", desc.GetReplicaFactor(), strings.Join(badReplicas, ",")))
+	}
+	for addr := range actual {
+		if !expected[addr] {
+			ds = append(ds, diff("storage hosts", joinKeys(expected), joinKeys(actual)))
+			break
+		}
+	}
+	return ds, nil
+}
+
+func joinKeys(m map[string]bool) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// Spaces compares the cluster with the backup and shows the discrepancies,
+// which are returned as one error so that they are reported one by one.
+func Spaces(m SpaceReader, backup *meta.BackupMeta, storageMap map[string]string) error {
+	backupName := string(backup.GetBackupName())
+	ds, err := Compare(m, backup, storageMap)
+	if err != nil {
+		return err
+	}
+	if len(ds) == 0 {
+		log.WithField("backup", backupName).WithField("spaces", len(backup.GetSpaceBackups())).
+			Info("Validate spaces successfully.")
+		return nil
+	}
+
+	asciiTable := make([][]string, 0, len(ds))
+	errs := make(utils.MultiError, 0, len(ds))
+	for _, d := range ds {
+		log.WithField("space", d.Space).WithField("item", d.Item).
+			WithField("expected", d.Expected).WithField("actual", d.Actual).Error("Space is not consistent with backup.")
+		asciiTable = append(asciiTable, []string{d.Space, d.Item, d.Expected, d.Actual})
+		errs = append(errs, d)
+	}
+	tw := tablewriter.NewWriter(os.Stdout)
+	tw.SetHeader([]string{"space", "item", "backup", "cluster"})
+	tw.AppendBulk(asciiTable)
+	tw.Render()
+
+	return fmt.Errorf("cluster is not consistent with backup %s: %w", backupName, errs)
+}
+
+// Validate checks a restored cluster against the backup it is restored from
+type Validate struct {
+	ctx   context.Context
+	cfg   *config.ValidateConfig
+	sto   storage.ExternalStorage
+	meta  *clients.NebulaMeta
+	hosts *utils.NebulaHosts
+}
+
+func NewValidate(ctx context.Context, cfg *config.ValidateConfig) (*Validate, error) {
+	sto, err := storage.New(cfg.Backend)
+	if err != nil {
+		return nil, fmt.Errorf("create storage for %s failed: %w", cfg.Backend.Uri(), err)
+	}
+
+	client, err := clients.NewMeta(cfg.MetaAddr, cfg.Conn)
+	if err != nil {
+		return nil, fmt.Errorf("create meta client failed: %w", err)
+	}
+
+	listRes, err := client.ListCluster()
+	if err != nil {
+		return nil, fmt.Errorf("list cluster failed: %w", err)
+	}
+	hosts := &utils.NebulaHosts{}
+	err = hosts.LoadFrom(listRes)
+	if err != nil {
+		return nil, fmt.Errorf("parse cluster response failed: %w", err)
+	}
+
+	return &Validate{
+		ctx:   ctx,
+		cfg:   cfg,
+		sto:   sto,
+		meta:  client,
+		hosts: hosts,
+	}, nil
+}
+
+// selectSpaces returns a copy of the backup meta with only the spaces named, as restored by
+// restore full --spaces, so the other spaces in backup or cluster are not compared.
+func selectSpaces(backup *meta.BackupMeta, names []string) (*meta.BackupMeta, error) {
+	if len(names) == 0 {
+		return backup, nil
+	}
+
+	ids := make(map[string]nebula.GraphSpaceID)
+	for sid, sb := range backup.GetSpaceBackups() {
+		ids[string(sb.GetSpace().GetSpaceName())] = sid
+	}
+	selected := *backup
+	selected.AllSpaces = false
+	selected.SpaceBackups = make(map[nebula.GraphSpaceID]*meta.SpaceBackupInfo)
+	for _, name := range names {
+		sid, ok := ids[name]
+		if !ok {
+			return nil, fmt.Errorf("space %s does not exist in backup %s", name, string(backup.GetBackupName()))
+		}
+		selected.SpaceBackups[sid] = backup.GetSpaceBackups()[sid]
+	}
+	return &selected, nil
+}
+
+func (v *Validate) Validate() error {
+	defer v.meta.Close()
+
+	if err := utils.EnsureDir(utils.LocalTmpDir); err != nil {
+		return err
+	}
+	defer func() {
+		if err := utils.RemoveDir(utils.LocalTmpDir); err != nil {
+			log.WithError(err).Errorf("Remove tmp dir %s failed.", utils.LocalTmpDir)
+		}
+	}()
+
+	backup, err := utils.DownloadMetaFile(v.ctx, v.sto, v.cfg.Backend.Uri(), v.cfg.BackupName, v.cfg.EncryptionKey)
+	if err != nil {
+		return fmt.Errorf("get backup meta file failed: %w", err)
+	}
+	// pair the storaged as restore does, so the partitions are expected on the same hosts
+	pairs, err := config.PairStorages(backup, v.hosts.GetStorages(), v.cfg.HostMap)
+	if err != nil {
+		return fmt.Errorf("pair storages in backup and cluster failed: %w", err)
+	}
+
+	selected, err := selectSpaces(backup, v.cfg.Spaces)
+	if err != nil {
+		return err
+	}
+	if err = Spaces(v.meta, selected, config.StorageMap(pairs)); err != nil {
+		return err
+	}
+	fmt.Printf("Cluster is validated, %d spaces are all consistent with backup %s.\n",
+		len(selected.GetSpaceBackups()), v.cfg.BackupName)
+	return nil
+}
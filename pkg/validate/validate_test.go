@@ -0,0 +1,212 @@
+package validate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vesoft-inc/nebula-go/v3/nebula"
+	"github.com/vesoft-inc/nebula-go/v3/nebula/meta"
+)
+
+// fakeCluster serves the spaces and their partition allocation like meta service
+type fakeCluster struct {
+	spaces map[string]*meta.SpaceItem
+	parts  map[nebula.GraphSpaceID]map[nebula.PartitionID][]*nebula.HostAddr
+}
+
+func (f *fakeCluster) GetSpace(space []byte) (*meta.GetSpaceResp, error) {
+	item, ok := f.spaces[string(space)]
+	if !ok {
+		return &meta.GetSpaceResp{Code: nebula.ErrorCode_E_SPACE_NOT_FOUND}, nil
+	}
+	return &meta.GetSpaceResp{Code: nebula.ErrorCode_SUCCEEDED, Item: item}, nil
+}
+
+func (f *fakeCluster) ListSpaces() ([]*meta.IdName, error) {
+	spaces := make([]*meta.IdName, 0, len(f.spaces))
+	for name, item := range f.spaces {
+		sid := item.GetSpaceID()
+		spaces = append(spaces, &meta.IdName{Id: &meta.ID{SpaceID: &sid}, Name: []byte(name)})
+	}
+	return spaces, nil
+}
+
+func (f *fakeCluster) GetPartsAlloc(spaceID nebula.GraphSpaceID) (map[nebula.PartitionID][]*nebula.HostAddr, error) {
+	return f.parts[spaceID], nil
+}
+
+func storaged(host string) *nebula.HostAddr {
+	return &nebula.HostAddr{Host: host, Port: 9779}
+}
+
+func spaceDesc(name string, partNum, replica int32) *meta.SpaceDesc {
+	return &meta.SpaceDesc{SpaceName: []byte(name), PartitionNum: partNum, ReplicaFactor: replica}
+}
+
+// newCluster returns the backup of space basic with 2 partitions on 192.168.0.1 and 192.168.0.2,
+// and the cluster restored from it to 10.0.0.1 and 10.0.0.2
+func newCluster() (*meta.BackupMeta, *fakeCluster, map[string]string) {
+	backup := &meta.BackupMeta{
+		BackupName: []byte("BACKUP_2022_03_01_10_00_00"),
+		AllSpaces:  true,
+		SpaceBackups: map[nebula.GraphSpaceID]*meta.SpaceBackupInfo{
+			1: {
+				Space: spaceDesc("basic", 2, 1),
+				HostBackups: []*meta.HostBackupInfo{
+					{Host: storaged("192.168.0.1")},
+					{Host: storaged("192.168.0.2")},
+				},
+			},
+		},
+	}
+	cluster := &fakeCluster{
+		spaces: map[string]*meta.SpaceItem{
+			"basic": {SpaceID: 1, Properties: spaceDesc("basic", 2, 1)},
+		},
+		parts: map[nebula.GraphSpaceID]map[nebula.PartitionID][]*nebula.HostAddr{
+			1: {
+				1: {storaged("10.0.0.1")},
+				2: {storaged("10.0.0.2")},
+			},
+		},
+	}
+	storageMap := map[string]string{
+		"192.168.0.1:9779": "10.0.0.1:9779",
+		"192.168.0.2:9779": "10.0.0.2:9779",
+	}
+	return backup, cluster, storageMap
+}
+
+func TestCompareSpace(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		name   string
+		modify func(c *fakeCluster, storageMap map[string]string)
+		items  []string
+	}{
+		{
+			name:   "consistent",
+			modify: func(c *fakeCluster, storageMap map[string]string) {},
+		},
+		{
+			name: "not existed",
+			modify: func(c *fakeCluster, storageMap map[string]string) {
+				delete(c.spaces, "basic")
+			},
+			items: []string{"existence"},
+		},
+		{
+			name: "space id and properties changed",
+			modify: func(c *fakeCluster, storageMap map[string]string) {
+				c.spaces["basic"] = &meta.SpaceItem{SpaceID: 2, Properties: spaceDesc("basic", 3, 3)}
+				c.parts[2] = c.parts[1]
+			},
+			items: []string{"space id", "partition num", "replica factor"},
+		},
+		{
+			name: "partitions allocated differently",
+			modify: func(c *fakeCluster, storageMap map[string]string) {
+				c.parts[1][1] = append(c.parts[1][1], storaged("10.0.0.2"))
+				c.parts[1][3] = []*nebula.HostAddr{storaged("10.0.0.1")}
+			},
+			items: []string{"allocated partitions", "partition replicas"},
+		},
+		{
+			name: "partition on unmapped host",
+			modify: func(c *fakeCluster, storageMap map[string]string) {
+				c.parts[1][2] = []*nebula.HostAddr{storaged("10.0.0.3")}
+			},
+			items: []string{"storage hosts"},
+		},
+		{
+			name: "partition on the other mapped host",
+			modify: func(c *fakeCluster, storageMap map[string]string) {
+				c.parts[1][1] = []*nebula.HostAddr{storaged("10.0.0.2")}
+			},
+		},
+		{
+			name: "storaged not mapped are expected as in backup",
+			modify: func(c *fakeCluster, storageMap map[string]string) {
+				delete(storageMap, "192.168.0.2:9779")
+			},
+			items: []string{"storage hosts"},
+		},
+	}
+
+	for _, tt := range tests {
+		backup, cluster, storageMap := newCluster()
+		tt.modify(cluster, storageMap)
+		ds, err := compareSpace(cluster, 1, backup.GetSpaceBackups()[1], storageMap)
+		assert.Nil(err, tt.name)
+
+		items := make([]string, 0, len(ds))
+		for _, d := range ds {
+			assert.Equal("basic", d.Space, tt.name)
+			items = append(items, d.Item)
+		}
+		if len(tt.items) == 0 {
+			assert.Empty(items, tt.name)
+		} else {
+			assert.Equal(tt.items, items, tt.name)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	assert := assert.New(t)
+
+	backup, cluster, storageMap := newCluster()
+	ds, err := Compare(cluster, backup, storageMap)
+	assert.Nil(err)
+	assert.Empty(ds)
+
+	// the space created after backup is reported only if all spaces are restored
+	cluster.spaces["nba"] = &meta.SpaceItem{SpaceID: 3, Properties: spaceDesc("nba", 1, 1)}
+	delete(cluster.spaces, "basic")
+	ds, err = Compare(cluster, backup, storageMap)
+	assert.Nil(err)
+	assert.Len(ds, 2)
+	assert.Equal("basic", ds[0].Space)
+	assert.Equal("existence", ds[0].Item)
+	assert.Equal("not existed", ds[0].Actual)
+	assert.Equal("nba", ds[1].Space)
+	assert.Equal("existence", ds[1].Item)
+	assert.Equal("existed", ds[1].Actual)
+
+	backup.AllSpaces = false
+	ds, err = Compare(cluster, backup, storageMap)
+	assert.Nil(err)
+	assert.Len(ds, 1)
+	assert.Equal("basic", ds[0].Space)
+	assert.NotNil(Spaces(cluster, backup, storageMap))
+}
+
+func TestSelectSpaces(t *testing.T) {
+	assert := assert.New(t)
+
+	backup, cluster, storageMap := newCluster()
+	backup.SpaceBackups[2] = &meta.SpaceBackupInfo{
+		Space:       spaceDesc("nba", 1, 1),
+		HostBackups: []*meta.HostBackupInfo{{Host: storaged("192.168.0.1")}},
+	}
+	selected, err := selectSpaces(backup, nil)
+	assert.Nil(err)
+	assert.Equal(backup, selected)
+
+	// nba is not restored by restore full --spaces basic, so it is not compared
+	selected, err = selectSpaces(backup, []string{"basic"})
+	assert.Nil(err)
+	assert.False(selected.GetAllSpaces())
+	assert.Len(selected.GetSpaceBackups(), 1)
+	assert.Contains(selected.GetSpaceBackups(), nebula.GraphSpaceID(1))
+	assert.Len(backup.GetSpaceBackups(), 2)
+	ds, err := Compare(cluster, selected, storageMap)
+	assert.Nil(err)
+	assert.Empty(ds)
+
+	_, err = selectSpaces(backup, []string{"basic", "test"})
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "space test does not exist in backup")
+}
@@ -0,0 +1,318 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vesoft-inc/nebula-agent/pkg/storage"
+	"github.com/vesoft-inc/nebula-br/pkg/config"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+	"github.com/vesoft-inc/nebula-go/v3/nebula/meta"
+)
+
+// Verify checks the files of a backup in external storage against its manifest,
+// or only its structure if it has no manifest, it does not need the nebula cluster.
+type Verify struct {
+	ctx context.Context
+	cfg *config.VerifyConfig
+	sto storage.ExternalStorage
+}
+
+func NewVerify(ctx context.Context, cfg *config.VerifyConfig) (*Verify, error) {
+	sto, err := storage.New(cfg.Backend)
+	if err != nil {
+		return nil, fmt.Errorf("create storage for %s failed: %w", cfg.Backend.Uri(), err)
+	}
+
+	return &Verify{
+		ctx: ctx,
+		cfg: cfg,
+		sto: sto,
+	}, nil
+}
+
+// collect downloads all the files recorded in manifest and compute their checksums
+func (v *Verify) collect(backupUri string, m *utils.Manifest) []*utils.ManifestFile {
+	var actual []*utils.ManifestFile
+	for _, dir := range m.Dirs {
+		files, err := utils.ChecksumDir(v.ctx, v.sto, backupUri, dir)
+		if err != nil {
+			log.WithError(err).WithField("dir", dir).Error("Compute checksum of backup dir failed.")
+			continue
+		}
+		actual = append(actual, files...)
+	}
+
+	// files not in the dirs, such as the backup meta file
+	for _, f := range m.Files {
+		inDir := false
+		for _, dir := range m.Dirs {
+			if strings.HasPrefix(f.Path, dir+"/") {
+				inDir = true
+				break
+			}
+		}
+		if inDir {
+			continue
+		}
+
+		af, err := utils.ChecksumFile(v.ctx, v.sto, backupUri, f.Path)
+		if err != nil {
+			log.WithError(err).WithField("file", f.Path).Error("Compute checksum of backup file failed.")
+			continue
+		}
+		actual = append(actual, af)
+	}
+
+	return actual
+}
+
+// extraEntries finds the unexpected entries in backup root dir and data dir,
+// which will not be downloaded when collecting
+func (v *Verify) extraEntries(backupUri string, m *utils.Manifest) []string {
+	expected := make(map[string]bool)
+	for _, p := range []string{"meta", "data", utils.ManifestName(v.cfg.BackupName), utils.RestoreJournalName} {
+		expected[p] = true
+	}
+	for _, f := range m.Files {
+		expected[f.Path] = true
+	}
+	for _, dir := range m.Dirs {
+		// data/{addr}/data{0..n}/{spaceId}
+		parts := strings.SplitN(dir, "/", 3)
+		if len(parts) >= 2 {
+			expected[path.Join(parts[0], parts[1])] = true
+		}
+	}
+
+	var extra []string
+	for _, dir := range []string{"", "data"} {
+		uri, _ := utils.UriJoin(backupUri, dir)
+		names, err := v.sto.ListDir(v.ctx, uri)
+		if err != nil {
+			log.WithError(err).WithField("uri", uri).Error("List backup dir failed.")
+			continue
+		}
+		for _, name := range names {
+			p := path.Join(dir, strings.Trim(name, "/"))
+			if !expected[p] {
+				extra = append(extra, p)
+			}
+		}
+	}
+	return extra
+}
+
+// structure checks the dirs and meta files recorded in the backup meta exist in storage,
+// it is used when the backup has no manifest, so the checksums are not checked.
+// It returns the missing paths and the count of paths checked.
+func (v *Verify) structure(backupUri string, bakMeta *meta.BackupMeta) ([]string, int) {
+	var missing []string
+	checked := 0
+
+	metaUri, _ := utils.UriJoin(backupUri, "meta")
+	metaFiles := v.listNames(metaUri)
+	for _, f := range bakMeta.GetMetaFiles() {
+		checked++
+		name := path.Base(string(f))
+		if !metaFiles[name] {
+			missing = append(missing, path.Join("meta", name))
+		}
+	}
+
+	for sid, sb := range bakMeta.GetSpaceBackups() {
+		for _, hb := range sb.GetHostBackups() {
+			for i := range hb.GetCheckpoints() {
+				checked++
+				dir := path.Join("data", utils.StringifyAddr(hb.GetHost()), fmt.Sprintf("data%d", i), fmt.Sprintf("%d", sid))
+				uri, _ := utils.UriJoin(backupUri, dir)
+				if !v.sto.ExistDir(v.ctx, uri) {
+					missing = append(missing, dir)
+				}
+			}
+		}
+	}
+
+	sort.Strings(missing)
+	return missing, checked
+}
+
+// listNames returns the entry names in the dir, empty if it could not be listed
+func (v *Verify) listNames(uri string) map[string]bool {
+	names := make(map[string]bool)
+	entries, err := v.sto.ListDir(v.ctx, uri)
+	if err != nil {
+		log.WithError(err).WithField("uri", uri).Error("List backup dir failed.")
+		return names
+	}
+	for _, e := range entries {
+		names[strings.Trim(e, "/")] = true
+	}
+	return names
+}
+
+// inherited checks the files an incremental backup shares with its bases,
+// each file should be recorded in the manifest of the backup holding it, if it has one,
+// and exist in its dir. It returns the missing files as {owner}/data/{dir}/{file}.
+func (v *Verify) inherited(refs *utils.BackupRefs) []string {
+	root := v.cfg.Backend.Uri()
+	owners := make(map[string]bool)
+	manifests := make(map[string]map[string]bool) // owner -> path in manifest, nil if no manifest
+	listed := make(map[string]map[string]bool)    // dir uri -> file names
+
+	var missing []string
+	for dir, files := range refs.Files {
+		for file, owner := range files {
+			p := path.Join(owner, "data", dir, file)
+
+			exist, ok := owners[owner]
+			if !ok {
+				ownerUri, _ := utils.UriJoin(root, owner)
+				exist = v.sto.ExistDir(v.ctx, ownerUri)
+				owners[owner] = exist
+				if !exist {
+					log.WithField("backup", owner).Error("The backup holding the inherited files does not exist.")
+				}
+			}
+			if !exist {
+				missing = append(missing, p)
+				continue
+			}
+
+			paths, ok := manifests[owner]
+			if !ok {
+				m, err := utils.DownloadManifest(v.ctx, v.sto, root, owner)
+				if err != nil {
+					log.WithError(err).WithField("backup", owner).
+						Warn("Get manifest of the backup holding the inherited files failed, only check their existence.")
+				} else {
+					paths = make(map[string]bool, len(m.Files))
+					for _, f := range m.Files {
+						paths[f.Path] = true
+					}
+				}
+				manifests[owner] = paths
+			}
+			if paths != nil && !paths[path.Join("data", dir, file)] {
+				missing = append(missing, p)
+				continue
+			}
+
+			dirUri, _ := utils.UriJoin(root, owner, "data", dir)
+			names, ok := listed[dirUri]
+			if !ok {
+				names = v.listNames(dirUri)
+				listed[dirUri] = names
+			}
+			if !names[file] {
+				missing = append(missing, p)
+			}
+		}
+	}
+
+	sort.Strings(missing)
+	return missing
+}
+
+func (v *Verify) Verify() error {
+	logger := log.WithField("backup", v.cfg.BackupName)
+
+	backupUri, err := utils.UriJoin(v.cfg.Backend.Uri(), v.cfg.BackupName)
+	if err != nil {
+		return err
+	}
+	if !v.sto.ExistDir(v.ctx, backupUri) {
+		return fmt.Errorf("backup dir %s does not exist", backupUri)
+	}
+
+	if err := utils.EnsureDir(utils.LocalTmpDir); err != nil {
+		return err
+	}
+	defer func() {
+		if err := utils.RemoveDir(utils.LocalTmpDir); err != nil {
+			log.WithError(err).Errorf("Remove tmp dir %s failed.", utils.LocalTmpDir)
+		}
+	}()
+
+	bakMeta, err := utils.DownloadMetaFile(v.ctx, v.sto, v.cfg.Backend.Uri(), v.cfg.BackupName, v.cfg.EncryptionKey)
+	if err != nil {
+		return fmt.Errorf("get backup meta failed, the backup may be broken: %w", err)
+	}
+
+	var (
+		missing, extra, corrupted []string
+		checked                   int
+		checksummed               bool
+	)
+	m, err := utils.DownloadManifest(v.ctx, v.sto, v.cfg.Backend.Uri(), v.cfg.BackupName)
+	if err != nil {
+		logger.WithError(err).Warn("Get manifest failed, the backup may be created without manifest, only check its structure.")
+		missing, checked = v.structure(backupUri, bakMeta)
+	} else {
+		if m.BackupName != v.cfg.BackupName {
+			return fmt.Errorf("backup name in manifest %s is not consistent with %s", m.BackupName, v.cfg.BackupName)
+		}
+		logger.WithField("files", len(m.Files)).Info("Get backup manifest successfully.")
+
+		actual := v.collect(backupUri, m)
+		missing, extra, corrupted = utils.CompareManifestFiles(m.Files, actual)
+		extra = append(extra, v.extraEntries(backupUri, m)...)
+		checked, checksummed = len(m.Files), true
+	}
+
+	if len(bakMeta.GetBaseBackupName()) != 0 {
+		refs, err := utils.DownloadRefs(v.ctx, v.sto, v.cfg.Backend.Uri(), v.cfg.BackupName, v.cfg.EncryptionKey)
+		if err != nil {
+			return fmt.Errorf("get refs of incremental backup failed, the backup may be broken: %w", err)
+		}
+		inherited := v.inherited(refs)
+		missing = append(missing, inherited...)
+		for _, files := range refs.Files {
+			checked += len(files)
+		}
+		logger.WithField("base", refs.Base).WithField("missing", len(inherited)).Info("Check the inherited files.")
+	}
+
+	v.showResult(missing, extra, corrupted)
+	if len(missing)+len(extra)+len(corrupted) != 0 {
+		return fmt.Errorf("backup %s is broken: %d missing, %d extra, %d corrupted files",
+			v.cfg.BackupName, len(missing), len(extra), len(corrupted))
+	}
+
+	if checksummed {
+		fmt.Printf("Backup %s is verified, %d files are all consistent with the manifest.\n",
+			v.cfg.BackupName, checked)
+	} else {
+		fmt.Printf("Backup %s has no manifest, %d dirs and files exist as recorded in its meta, checksums are skipped.\n",
+			v.cfg.BackupName, checked)
+	}
+	return nil
+}
+
+func (v *Verify) showResult(missing, extra, corrupted []string) {
+	asciiTable := make([][]string, 0)
+	for _, p := range missing {
+		asciiTable = append(asciiTable, []string{"missing", p})
+	}
+	for _, p := range extra {
+		asciiTable = append(asciiTable, []string{"extra", p})
+	}
+	for _, p := range corrupted {
+		asciiTable = append(asciiTable, []string{"corrupted", p})
+	}
+	if len(asciiTable) == 0 {
+		return
+	}
+
+	tw := tablewriter.NewWriter(os.Stdout)
+	tw.SetHeader([]string{"problem", "path"})
+	tw.AppendBulk(asciiTable)
+	tw.Render()
+}
@@ -0,0 +1,149 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/vesoft-inc/nebula-agent/pkg/proto"
+	"github.com/vesoft-inc/nebula-br/pkg/config"
+	"github.com/vesoft-inc/nebula-br/pkg/utils"
+	"github.com/vesoft-inc/nebula-go/v3/nebula"
+	"github.com/vesoft-inc/nebula-go/v3/nebula/meta"
+)
+
+// fakeStorage keeps the files in memory by their uri
+type fakeStorage struct {
+	files map[string]string
+}
+
+func (f *fakeStorage) Upload(ctx context.Context, externalPath, localPath string, recursively bool) error {
+	return fmt.Errorf("not supported")
+}
+
+func (f *fakeStorage) Download(ctx context.Context, localPath, externalPath string, recursively bool) error {
+	content, ok := f.files[externalPath]
+	if !ok {
+		return fmt.Errorf("%s does not exist", externalPath)
+	}
+	return ioutil.WriteFile(localPath, []byte(content), 0644)
+}
+
+func (f *fakeStorage) ExistDir(ctx context.Context, uri string) bool {
+	for p := range f.files {
+		if strings.HasPrefix(p, uri+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeStorage) EnsureDir(ctx context.Context, uri string, recursively bool) error {
+	return nil
+}
+
+func (f *fakeStorage) RemoveDir(ctx context.Context, uri string) error {
+	return fmt.Errorf("not supported")
+}
+
+func (f *fakeStorage) ListDir(ctx context.Context, uri string) ([]string, error) {
+	seen := make(map[string]bool)
+	var names []string
+	for p := range f.files {
+		if !strings.HasPrefix(p, uri+"/") {
+			continue
+		}
+		name := strings.SplitN(strings.TrimPrefix(p, uri+"/"), "/", 2)[0]
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func (f *fakeStorage) GetDir(ctx context.Context, uri string) (*pb.Backend, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+const root = "local:///backup"
+
+func newFakeVerify(t *testing.T, files map[string]string) *Verify {
+	backend := &pb.Backend{}
+	assert.Nil(t, backend.SetUri(root))
+	return &Verify{
+		ctx: context.TODO(),
+		cfg: &config.VerifyConfig{BackupName: "BACKUP_INCR", Backend: backend},
+		sto: &fakeStorage{files: files},
+	}
+}
+
+func TestInherited(t *testing.T) {
+	assert := assert.New(t)
+	assert.Nil(utils.EnsureDir(utils.LocalTmpDir))
+	defer utils.RemoveDir(utils.LocalTmpDir)
+
+	dir := "192.168.0.1:9779/data0/1/data"
+	m, err := json.Marshal(&utils.Manifest{
+		BackupName: "BACKUP_FULL",
+		Files: []*utils.ManifestFile{
+			{Path: "data/" + dir + "/1.sst"},
+			{Path: "data/" + dir + "/2.sst"},
+		},
+	})
+	assert.Nil(err)
+	files := map[string]string{
+		root + "/BACKUP_FULL/" + utils.ManifestName("BACKUP_FULL"): string(m),
+		root + "/BACKUP_FULL/data/" + dir + "/1.sst":               "",
+		root + "/BACKUP_FULL/data/" + dir + "/3.sst":               "",
+		root + "/BACKUP_NO_MANIFEST/data/" + dir + "/4.sst":        "",
+	}
+	refs := utils.NewBackupRefs("BACKUP_FULL")
+	refs.Add(dir, "1.sst", "BACKUP_FULL")
+	refs.Add(dir, "2.sst", "BACKUP_FULL")        // gone from storage
+	refs.Add(dir, "3.sst", "BACKUP_FULL")        // not recorded in manifest
+	refs.Add(dir, "4.sst", "BACKUP_NO_MANIFEST") // only the existence is checked
+	refs.Add(dir, "5.sst", "BACKUP_NO_MANIFEST")
+	refs.Add(dir, "6.sst", "BACKUP_REMOVED")
+
+	v := newFakeVerify(t, files)
+	assert.Equal([]string{
+		"BACKUP_FULL/data/" + dir + "/2.sst",
+		"BACKUP_FULL/data/" + dir + "/3.sst",
+		"BACKUP_NO_MANIFEST/data/" + dir + "/5.sst",
+		"BACKUP_REMOVED/data/" + dir + "/6.sst",
+	}, v.inherited(refs))
+}
+
+func TestStructure(t *testing.T) {
+	assert := assert.New(t)
+
+	bakMeta := &meta.BackupMeta{
+		MetaFiles: [][]byte{[]byte("__edges__.sst"), []byte("__tags__.sst")},
+		SpaceBackups: map[nebula.GraphSpaceID]*meta.SpaceBackupInfo{
+			1: {
+				HostBackups: []*meta.HostBackupInfo{
+					{
+						Host:        &nebula.HostAddr{Host: "192.168.0.1", Port: 9779},
+						Checkpoints: []*nebula.CheckpointInfo{{}, {}},
+					},
+				},
+			},
+		},
+	}
+	backupUri := root + "/BACKUP_INCR"
+	files := map[string]string{
+		backupUri + "/meta/__edges__.sst":                       "",
+		backupUri + "/data/192.168.0.1:9779/data0/1/data/1.sst": "",
+	}
+
+	v := newFakeVerify(t, files)
+	missing, checked := v.structure(backupUri, bakMeta)
+	assert.Equal(4, checked)
+	assert.Equal([]string{"data/192.168.0.1:9779/data1/1", "meta/__tags__.sst"}, missing)
+}